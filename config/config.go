@@ -2,25 +2,36 @@ package config
 
 import (
 	"time"
-
-	"golang.org/x/oauth2"
-	oauth2github "golang.org/x/oauth2/github"
-	oauth2google "golang.org/x/oauth2/google"
 )
 
 type (
 	Config struct {
-		App         `yaml:"app"`
-		HTTP        `yaml:"http"`
-		Log         `yaml:"logger"`
-		PG          `yaml:"postgres"`
-		MongoDB     `yaml:"mongodb"`
-		Cache       `yaml:"cache"`
-		Redis       `yaml:"redis"`
-		SocialAuth  `yaml:"social_auth"`
-		Session     `yaml:"session"`
-		AccessToken `yaml:"access_token"`
-		CSRFToken `yaml:"csrf_token"`
+		App            `yaml:"app"`
+		HTTP           `yaml:"http"`
+		Log            `yaml:"logger"`
+		PG             `yaml:"postgres"`
+		MongoDB        `yaml:"mongodb"`
+		Cache          `yaml:"cache"`
+		Redis          `yaml:"redis"`
+		SocialAuth     `yaml:"social_auth"`
+		Session        `yaml:"session"`
+		AccessToken    `yaml:"access_token"`
+		CSRFToken      `yaml:"csrf_token"`
+		SMTP           `yaml:"smtp"`
+		Mailer         `yaml:"mailer"`
+		Verification   `yaml:"verification"`
+		PasswordReset  `yaml:"password_reset"`
+		EmailChange    `yaml:"email_change"`
+		AccountRestore `yaml:"account_restore"`
+		Erasure        `yaml:"erasure"`
+		Availability   `yaml:"availability"`
+		MFA            `yaml:"mfa"`
+		WebAuthn       `yaml:"webauthn"`
+		MagicLink      `yaml:"magic_link"`
+		BruteForce     `yaml:"brute_force"`
+		RateLimit      `yaml:"rate_limit"`
+		PasswordHash   `yaml:"password_hash"`
+		PasswordPolicy `yaml:"password_policy"`
 	}
 
 	App struct {
@@ -58,14 +69,33 @@ type (
 		Password string `env-required:"true" env:"REDIS_PASSWORD"`
 	}
 
+	// SocialAuth contains social login providers by name which is used in routes,
+	// ClientSecrets are client secrets by provider name, e.g. "github:<secret>,google:<secret>".
+	// OAuth state is valid for StateTTL in browser which has cookie with StateCookieKey.
 	SocialAuth struct {
-		GitHubClientID     string `yaml:"github_client_id" env-required:"true" env:"GH_CLIENT_ID"`
-		GitHubClientSecret string `env-required:"true" env:"GH_CLIENT_SECRET"`
-		GitHubScope        string `yaml:"github_scope" env-required:"true" env:"GH_SCOPE"`
+		Providers      map[string]SocialProvider `yaml:"providers"`
+		ClientSecrets  map[string]string         `env:"SOCIAL_AUTH_CLIENT_SECRETS"`
+		StateTTL       time.Duration             `env-required:"true" yaml:"state_ttl" env:"SOCIAL_AUTH_STATE_TTL"`
+		StateCookieKey string                    `env-required:"true" yaml:"state_cookie_key" env:"SOCIAL_AUTH_STATE_COOKIE_KEY"`
+	}
+
+	// SocialProvider is configured by issuer URL with OpenID Connect discovery if Type is "oidc",
+	// "github" type is OAuth2 provider which uses GitHub API to get user.
+	SocialProvider struct {
+		Type          string       `yaml:"type"`
+		Issuer        string       `yaml:"issuer"`
+		IssuerAliases []string     `yaml:"issuer_aliases"`
+		ClientID      string       `yaml:"client_id"`
+		RedirectURL   string       `yaml:"redirect_url"`
+		Scopes        []string     `yaml:"scopes"`
+		Claims        SocialClaims `yaml:"claims"`
+	}
 
-		GoogleClientID     string `yaml:"google_client_id" env-required:"true" env:"GOOGLE_CLIENT_ID"`
-		GoogleClientSecret string `env-required:"true" env:"GOOGLE_CLIENT_SECRET"`
-		GoogleScope        string `yaml:"google_scope" env-required:"true" env:"GOOGLE_SCOPE"`
+	// SocialClaims are names of ID token claims holding user data, empty names fall back to standard claims.
+	SocialClaims struct {
+		Email         string `yaml:"email"`
+		EmailVerified string `yaml:"email_verified"`
+		Username      string `yaml:"username"`
 	}
 
 	Session struct {
@@ -82,38 +112,127 @@ type (
 	}
 
 	CSRFToken struct {
-		TTL        time.Duration `env-required:"true" yaml:"ttl" env:"CSRF_TOKEN_TTL"`
-		CookieKey      string        `env-required:"true" yaml:"cookie_key" env:"CSRF_TOKEN_COOKIE_KEY"`
-		HeaderKey      string        `env-required:"true" yaml:"header_key" env:"CSRF_TOKEN_HEADER_KEY"`
+		TTL       time.Duration `env-required:"true" yaml:"ttl" env:"CSRF_TOKEN_TTL"`
+		CookieKey string        `env-required:"true" yaml:"cookie_key" env:"CSRF_TOKEN_COOKIE_KEY"`
+		HeaderKey string        `env-required:"true" yaml:"header_key" env:"CSRF_TOKEN_HEADER_KEY"`
+	}
+
+	SMTP struct {
+		Host     string `env-required:"true" yaml:"host" env:"SMTP_HOST"`
+		Port     string `env-required:"true" yaml:"port" env:"SMTP_PORT"`
+		Username string `env:"SMTP_USERNAME"`
+		Password string `env:"SMTP_PASSWORD"`
+		From     string `env-required:"true" yaml:"from" env:"SMTP_FROM"`
 	}
-)
 
-func (sa *SocialAuth) Endpoints() map[string]oauth2.Endpoint {
-	return map[string]oauth2.Endpoint{
-		"github": oauth2github.Endpoint,
-		"google": oauth2google.Endpoint,
+	Mailer struct {
+		Driver string `env-required:"true" yaml:"driver" env:"MAILER_DRIVER"`
+		Dir    string `yaml:"dir" env:"MAILER_DIR"`
 	}
-}
 
-func (sa *SocialAuth) Scopes() map[string]string {
-	return map[string]string{
-		"github": sa.GitHubScope,
-		"google": sa.GoogleScope,
+	Verification struct {
+		CodeTTL        time.Duration `env-required:"true" yaml:"code_ttl" env:"VERIFICATION_CODE_TTL"`
+		ResendCooldown time.Duration `env-required:"true" yaml:"resend_cooldown" env:"VERIFICATION_RESEND_COOLDOWN"`
+		LoginRequired  bool          `yaml:"login_required" env:"VERIFICATION_LOGIN_REQUIRED"`
 	}
-}
 
-func (sa *SocialAuth) ClientIDs() map[string]string {
-	return map[string]string{
-		"github": sa.GitHubClientID,
-		"google": sa.GoogleClientID,
+	PasswordReset struct {
+		TokenTTL time.Duration `env-required:"true" yaml:"token_ttl" env:"PASSWORD_RESET_TOKEN_TTL"`
 	}
 
-}
+	EmailChange struct {
+		TokenTTL   time.Duration `env-required:"true" yaml:"token_ttl" env:"EMAIL_CHANGE_TOKEN_TTL"`
+		RevertTTL  time.Duration `env-required:"true" yaml:"revert_ttl" env:"EMAIL_CHANGE_REVERT_TTL"`
+		ConfirmURL string        `env-required:"true" yaml:"confirm_url" env:"EMAIL_CHANGE_CONFIRM_URL"`
+		RevertURL  string        `env-required:"true" yaml:"revert_url" env:"EMAIL_CHANGE_REVERT_URL"`
+	}
 
-func (sa *SocialAuth) ClientSecrets() map[string]string {
-	return map[string]string{
-		"github": sa.GitHubClientSecret,
-		"google": sa.GoogleClientSecret,
+	AccountRestore struct {
+		GracePeriod time.Duration `env-required:"true" yaml:"grace_period" env:"ACCOUNT_RESTORE_GRACE_PERIOD"`
+		TokenTTL    time.Duration `env-required:"true" yaml:"token_ttl" env:"ACCOUNT_RESTORE_TOKEN_TTL"`
+		URL         string        `env-required:"true" yaml:"url" env:"ACCOUNT_RESTORE_URL"`
+		OnLogin     bool          `yaml:"on_login" env:"ACCOUNT_RESTORE_ON_LOGIN"`
 	}
 
-}
+	Erasure struct {
+		Retention time.Duration `env-required:"true" yaml:"retention" env:"ERASURE_RETENTION"`
+		Interval  time.Duration `env-required:"true" yaml:"interval" env:"ERASURE_INTERVAL"`
+		BatchSize int           `env-required:"true" yaml:"batch_size" env:"ERASURE_BATCH_SIZE"`
+	}
+
+	Availability struct {
+		RateLimit  int           `env-required:"true" yaml:"rate_limit" env:"AVAILABILITY_RATE_LIMIT"`
+		RatePeriod time.Duration `env-required:"true" yaml:"rate_period" env:"AVAILABILITY_RATE_PERIOD"`
+	}
+
+	MFA struct {
+		Issuer        string        `env-required:"true" yaml:"issuer" env:"MFA_ISSUER"`
+		EncryptionKey string        `env-required:"true" env:"MFA_ENCRYPTION_KEY"`
+		ChallengeTTL  time.Duration `env-required:"true" yaml:"challenge_ttl" env:"MFA_CHALLENGE_TTL"`
+		MaxAttempts   int           `env-required:"true" yaml:"max_attempts" env:"MFA_MAX_ATTEMPTS"`
+		RecoveryCodes int           `env-required:"true" yaml:"recovery_codes" env:"MFA_RECOVERY_CODES"`
+		EmailCodeTTL  time.Duration `env-required:"true" yaml:"email_code_ttl" env:"MFA_EMAIL_CODE_TTL"`
+		EmailCooldown time.Duration `env-required:"true" yaml:"email_cooldown" env:"MFA_EMAIL_COOLDOWN"`
+	}
+
+	WebAuthn struct {
+		RPID        string        `env-required:"true" yaml:"rp_id" env:"WEBAUTHN_RP_ID"`
+		RPName      string        `env-required:"true" yaml:"rp_name" env:"WEBAUTHN_RP_NAME"`
+		Origin      string        `env-required:"true" yaml:"origin" env:"WEBAUTHN_ORIGIN"`
+		CeremonyTTL time.Duration `env-required:"true" yaml:"ceremony_ttl" env:"WEBAUTHN_CEREMONY_TTL"`
+	}
+
+	BruteForce struct {
+		LoginAttempts int           `env-required:"true" yaml:"login_attempts" env:"BRUTE_FORCE_LOGIN_ATTEMPTS"`
+		IPAttempts    int           `env-required:"true" yaml:"ip_attempts" env:"BRUTE_FORCE_IP_ATTEMPTS"`
+		BaseDelay     time.Duration `env-required:"true" yaml:"base_delay" env:"BRUTE_FORCE_BASE_DELAY"`
+		MaxDelay      time.Duration `env-required:"true" yaml:"max_delay" env:"BRUTE_FORCE_MAX_DELAY"`
+		Window        time.Duration `env-required:"true" yaml:"window" env:"BRUTE_FORCE_WINDOW"`
+	}
+
+	// PasswordHash contains parameters of algorithm used for new password hashes,
+	// hashes made with other algorithm or weaker parameters are upgraded on login.
+	PasswordHash struct {
+		Algorithm           string `env-required:"true" yaml:"algorithm" env:"PASSWORD_HASH_ALGORITHM"`
+		BcryptCost          int    `env-required:"true" yaml:"bcrypt_cost" env:"PASSWORD_HASH_BCRYPT_COST"`
+		Argon2idMemory      int    `env-required:"true" yaml:"argon2id_memory" env:"PASSWORD_HASH_ARGON2ID_MEMORY"`
+		Argon2idIterations  int    `env-required:"true" yaml:"argon2id_iterations" env:"PASSWORD_HASH_ARGON2ID_ITERATIONS"`
+		Argon2idParallelism int    `env-required:"true" yaml:"argon2id_parallelism" env:"PASSWORD_HASH_ARGON2ID_PARALLELISM"`
+		Argon2idSaltLength  int    `env-required:"true" yaml:"argon2id_salt_length" env:"PASSWORD_HASH_ARGON2ID_SALT_LENGTH"`
+		Argon2idKeyLength   int    `env-required:"true" yaml:"argon2id_key_length" env:"PASSWORD_HASH_ARGON2ID_KEY_LENGTH"`
+
+		// PepperVersion is version of pepper key applied to new passwords, 0 disables pepper.
+		// PepperKeys are hex encoded keys by version, e.g. "1:<key>,2:<key>".
+		PepperVersion int            `yaml:"pepper_version" env:"PASSWORD_PEPPER_VERSION"`
+		PepperKeys    map[int]string `env:"PASSWORD_PEPPER_KEYS"`
+	}
+
+	// PasswordPolicy is applied to new passwords, empty BreachedDir disables breached passwords check.
+	PasswordPolicy struct {
+		MinScore    int      `env-required:"true" yaml:"min_score" env:"PASSWORD_POLICY_MIN_SCORE"`
+		MinEntropy  float64  `env-required:"true" yaml:"min_entropy" env:"PASSWORD_POLICY_MIN_ENTROPY"`
+		Denylist    []string `yaml:"denylist" env:"PASSWORD_POLICY_DENYLIST"`
+		BreachedDir string   `yaml:"breached_dir" env:"PASSWORD_POLICY_BREACHED_DIR"`
+	}
+
+	// RateLimit contains token bucket policies applied to route groups by policy name.
+	RateLimit struct {
+		Policies map[string]RateLimitPolicy `yaml:"policies"`
+	}
+
+	// RateLimitPolicy allows Limit requests per Period keyed by "ip", "account" or "session".
+	RateLimitPolicy struct {
+		Limit  int           `yaml:"limit"`
+		Period time.Duration `yaml:"period"`
+		Key    string        `yaml:"key"`
+	}
+
+	MagicLink struct {
+		TokenTTL   time.Duration `env-required:"true" yaml:"token_ttl" env:"MAGIC_LINK_TOKEN_TTL"`
+		URL        string        `env-required:"true" yaml:"url" env:"MAGIC_LINK_URL"`
+		SigningKey string        `env-required:"true" env:"MAGIC_LINK_SIGNING_KEY"`
+		CookieKey  string        `env-required:"true" yaml:"cookie_key" env:"MAGIC_LINK_COOKIE_KEY"`
+		RateLimit  int           `env-required:"true" yaml:"rate_limit" env:"MAGIC_LINK_RATE_LIMIT"`
+		RatePeriod time.Duration `env-required:"true" yaml:"rate_period" env:"MAGIC_LINK_RATE_PERIOD"`
+	}
+)
@@ -1,12 +1,15 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 
 	"github.com/ysomad/go-auth-service/config"
 
@@ -14,12 +17,17 @@ import (
 	"github.com/ysomad/go-auth-service/internal/repository"
 	"github.com/ysomad/go-auth-service/internal/service"
 
+	"github.com/ysomad/go-auth-service/pkg/email"
+	"github.com/ysomad/go-auth-service/pkg/encryption"
 	"github.com/ysomad/go-auth-service/pkg/httpserver"
 	"github.com/ysomad/go-auth-service/pkg/jwt"
 	"github.com/ysomad/go-auth-service/pkg/logger"
 	"github.com/ysomad/go-auth-service/pkg/mongodb"
+	"github.com/ysomad/go-auth-service/pkg/password"
 	"github.com/ysomad/go-auth-service/pkg/postgres"
+	"github.com/ysomad/go-auth-service/pkg/ratelimit"
 	"github.com/ysomad/go-auth-service/pkg/validation"
+	"github.com/ysomad/go-auth-service/pkg/webauthn"
 )
 
 // Run creates objects via constructors.
@@ -41,28 +49,194 @@ func Run(cfg *config.Config) {
 	mdb := mcli.Database(cfg.MongoDB.Database)
 
 	// Redis
-	/*
-		rdb := redis.NewClient(&redis.Options{
-			Addr:     cfg.Redis.Addr,
-			Password: cfg.Redis.Password,
-			DB:       0,
-		})
-	*/
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       0,
+	})
+	defer rdb.Close()
+
+	redisAvailable := true
+
+	if err = rdb.Ping(context.Background()).Err(); err != nil {
+		l.Error(fmt.Errorf("app - Run - rdb.Ping, falling back to in-memory storage: %w", err))
+		redisAvailable = false
+	}
 
 	// Service
 	accountRepo := repository.NewAccountRepo(pg)
+	verificationRepo := repository.NewVerificationRepo(pg)
+	passwordResetRepo := repository.NewPasswordResetRepo(pg)
+	emailChangeRepo := repository.NewEmailChangeRepo(pg)
+	accountRestoreRepo := repository.NewAccountRestoreRepo(pg)
+	auditRepo := repository.NewAuditRepo(pg)
+	totpRepo := repository.NewTOTPRepo(pg)
+	emailOTPRepo := repository.NewEmailOTPRepo(pg)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepo(pg)
+	webAuthnCredentialRepo := repository.NewWebAuthnCredentialRepo(pg)
+	identityRepo := repository.NewIdentityRepo(pg)
+	magicLinkRepo := repository.NewMagicLinkRepo(pg)
 	sessionRepo := repository.NewSessionRepo(mdb)
+	mfaChallengeRepo := repository.NewMFAChallengeRepo(mdb)
+	webAuthnCeremonyRepo := repository.NewWebAuthnCeremonyRepo(mdb)
+	oauthStateRepo := repository.NewOAuthStateRepo(mdb)
+
+	var loginAttemptRepo service.LoginAttemptRepo = repository.NewLoginAttemptMemoryRepo()
+	if redisAvailable {
+		loginAttemptRepo = repository.NewLoginAttemptRedisRepo(rdb)
+	}
+
+	newLimiter := ratelimit.NewFunc(func(name string, limit int, period time.Duration) ratelimit.Limiter {
+		return ratelimit.NewMemory(limit, period)
+	})
+	if redisAvailable {
+		newLimiter = func(name string, limit int, period time.Duration) ratelimit.Limiter {
+			return ratelimit.NewRedis(rdb, "rateLimit:"+name+":", limit, period)
+		}
+	}
+
+	argon2id := password.NewArgon2id(password.Argon2idParams{
+		Memory:      uint32(cfg.PasswordHash.Argon2idMemory),
+		Iterations:  uint32(cfg.PasswordHash.Argon2idIterations),
+		Parallelism: uint8(cfg.PasswordHash.Argon2idParallelism),
+		SaltLength:  uint32(cfg.PasswordHash.Argon2idSaltLength),
+		KeyLength:   uint32(cfg.PasswordHash.Argon2idKeyLength),
+	})
+	bcrypt := password.NewBcrypt(cfg.PasswordHash.BcryptCost)
+
+	var passwordHasher *password.Hasher
+
+	switch cfg.PasswordHash.Algorithm {
+	case password.AlgorithmArgon2id:
+		passwordHasher = password.NewHasher(argon2id, bcrypt)
+	case password.AlgorithmBcrypt:
+		passwordHasher = password.NewHasher(bcrypt, argon2id)
+	default:
+		l.Fatal(fmt.Errorf("app - Run: %w", password.ErrUnsupportedHash))
+	}
+
+	passwordPepper, err := password.NewPepper(cfg.PasswordHash.PepperVersion, cfg.PasswordHash.PepperKeys)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - password.NewPepper: %w", err))
+	}
+
+	var breached password.BreachedList
+
+	if cfg.PasswordPolicy.BreachedDir != "" {
+		breached, err = password.NewBreachedDir(cfg.PasswordPolicy.BreachedDir)
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - password.NewBreachedDir: %w", err))
+		}
+	}
+
+	passwordPolicy := password.NewPolicy(
+		cfg.PasswordPolicy.MinScore,
+		cfg.PasswordPolicy.MinEntropy,
+		cfg.PasswordPolicy.Denylist,
+		breached,
+	)
+
+	var emailSender email.Sender
+
+	switch cfg.Mailer.Driver {
+	case "file":
+		emailSender, err = email.NewFileSender(cfg.Mailer.Dir, cfg.SMTP.From)
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - email.NewFileSender: %w", err))
+		}
+	case "memory":
+		emailSender = email.NewMemorySender()
+	default:
+		emailSender = email.NewSMTPSender(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	}
+
+	// emails are delivered off the request path, so responses do not reveal whether email was sent
+	backgroundSender := email.NewBackgroundSender(emailSender, func(err error) {
+		l.Error(fmt.Errorf("app - Run - email.Send: %w", err))
+	})
+	emailSender = backgroundSender
 
 	sessionService := service.NewSessionService(cfg, sessionRepo)
-	accountService := service.NewAccountService(cfg, accountRepo, sessionService)
+	auditService := service.NewAuditService(auditRepo)
+	accountService := service.NewAccountService(
+		cfg,
+		accountRepo,
+		verificationRepo,
+		passwordResetRepo,
+		emailChangeRepo,
+		accountRestoreRepo,
+		identityRepo,
+		sessionService,
+		auditService,
+		emailSender,
+		passwordHasher,
+		passwordPepper,
+		passwordPolicy,
+	)
+
+	if cfg.Erasure.Interval <= 0 {
+		l.Fatal(fmt.Errorf("app - Run: erasure interval must be positive, got %s", cfg.Erasure.Interval))
+	}
+
+	erasureService := service.NewErasureService(cfg, accountRepo, sessionService)
 
 	jwt, err := jwt.New(cfg.AccessToken.SigningKey, cfg.AccessToken.TTL)
 	if err != nil {
 		l.Fatal(fmt.Errorf("app - Run - auth.NewTokenManager: %w", err))
 	}
 
-	authService := service.NewAuthService(cfg, jwt, accountService, sessionService)
-	socialAuthService := service.NewSocialAuthService(cfg, accountService, sessionService)
+	mfaCipher, err := encryption.NewAESGCM(cfg.MFA.EncryptionKey)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - encryption.NewAESGCM: %w", err))
+	}
+
+	mfaService := service.NewMFAService(
+		cfg,
+		totpRepo,
+		emailOTPRepo,
+		mfaChallengeRepo,
+		recoveryCodeRepo,
+		accountService,
+		sessionService,
+		auditService,
+		mfaCipher,
+		emailSender,
+	)
+	authService := service.NewAuthService(
+		cfg,
+		jwt,
+		accountService,
+		sessionService,
+		mfaService,
+		service.NewLoginGuard(cfg, loginAttemptRepo),
+		magicLinkRepo,
+		emailSender,
+	)
+	socialProviders, err := service.NewSocialProviders(cfg)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - service.NewSocialProviders: %w", err))
+	}
+
+	socialAuthService := service.NewSocialAuthService(
+		cfg,
+		accountService,
+		sessionService,
+		mfaService,
+		auditService,
+		socialProviders,
+		oauthStateRepo,
+		identityRepo,
+		webAuthnCredentialRepo,
+	)
+	webAuthnService := service.NewWebAuthnService(
+		cfg,
+		webAuthnCredentialRepo,
+		webAuthnCeremonyRepo,
+		accountService,
+		sessionService,
+		auditService,
+		webauthn.New(cfg.WebAuthn.RPID, cfg.WebAuthn.RPName, cfg.WebAuthn.Origin),
+	)
 
 	v, err := validation.NewGinValidator()
 	if err != nil {
@@ -71,9 +245,27 @@ func Run(cfg *config.Config) {
 
 	// HTTP Server
 	handler := gin.New()
-	v1.SetupHandlers(handler, l, v, cfg, accountService, sessionService, authService, socialAuthService)
+	v1.SetupHandlers(
+		handler,
+		l,
+		v,
+		cfg,
+		newLimiter,
+		accountService,
+		sessionService,
+		authService,
+		socialAuthService,
+		mfaService,
+		webAuthnService,
+	)
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
+	// Purge worker
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go runPurge(ctx, l, erasureService, cfg.Erasure.Interval)
+
 	// Waiting signal
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
@@ -90,4 +282,6 @@ func Run(cfg *config.Config) {
 	if err != nil {
 		l.Error(fmt.Errorf("app - Run - httpServer.Shutdown: %w", err))
 	}
+
+	backgroundSender.Wait()
 }
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/internal/service"
+
+	"github.com/ysomad/go-auth-service/pkg/logger"
+)
+
+// runPurge periodically erases accounts archived longer than retention period until ctx is done.
+func runPurge(ctx context.Context, l logger.Interface, e service.Erasure, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		n, err := e.Purge(ctx)
+		if err != nil {
+			l.Error(fmt.Errorf("app - runPurge - e.Purge: %w", err))
+		}
+
+		if n > 0 {
+			l.Info(fmt.Sprintf("app - runPurge - erased accounts: %d", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
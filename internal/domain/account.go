@@ -4,44 +4,76 @@ import (
 	"fmt"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/password"
 	"github.com/ysomad/go-auth-service/pkg/utils"
 )
 
 // Account represents user data model
 type Account struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	Username     string    `json:"username"`
-	Password     string    `json:"-"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-	Archive      bool      `json:"archive"`
-	Verified     bool      `json:"verified"`
+	ID                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	Username              string     `json:"username"`
+	Password              string     `json:"-"`
+	PasswordHash          string     `json:"-"`
+	PasswordPepperVersion int        `json:"-"`
+	PasswordGenerated     bool       `json:"-"`
+	CreatedAt             time.Time  `json:"createdAt"`
+	UpdatedAt             time.Time  `json:"updatedAt"`
+	Archive               bool       `json:"archive"`
+	ArchivedAt            *time.Time `json:"archivedAt,omitempty"`
+	Verified              bool       `json:"verified"`
+	Version               int        `json:"version"`
+}
+
+// GeneratePasswordHash hashes password peppered with current pepper key.
+func (a *Account) GeneratePasswordHash(h *password.Hasher, p *password.Pepper) error {
+	peppered, err := p.Apply(a.Password, p.Version())
+	if err != nil {
+		return fmt.Errorf("p.Apply: %w", apperrors.ErrAccountPasswordNotGenerated)
+	}
+
+	hash, err := h.Hash(peppered)
+	if err != nil {
+		return fmt.Errorf("h.Hash: %w", apperrors.ErrAccountPasswordNotGenerated)
+	}
+
+	a.PasswordHash = hash
+	a.PasswordPepperVersion = p.Version()
+
+	return nil
 }
 
-func (a *Account) GeneratePasswordHash() error {
-	b, err := bcrypt.GenerateFromPassword([]byte(a.Password), 11)
+// CompareHashAndPassword compares password peppered with key of the hash pepper version with the hash.
+// Generated password never matches, it is compared anyway to not reveal accounts without password by timing.
+func (a *Account) CompareHashAndPassword(h *password.Hasher, p *password.Pepper) error {
+	peppered, err := p.Apply(a.Password, a.PasswordPepperVersion)
 	if err != nil {
-		return fmt.Errorf("bcrypt.GenerateFromPassword: %w", apperrors.ErrAccountPasswordNotGenerated)
+		return fmt.Errorf("p.Apply: %w", err)
 	}
 
-	a.PasswordHash = string(b)
+	if err = h.Verify(a.PasswordHash, peppered); err != nil || a.PasswordGenerated {
+		return fmt.Errorf("h.Verify: %w", apperrors.ErrAccountIncorrectPassword)
+	}
 
 	return nil
 }
 
-func (a *Account) CompareHashAndPassword() error {
-	if err := bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(a.Password)); err != nil {
-		return fmt.Errorf("bcrypt.CompareHashAndPassword: %w", apperrors.ErrAccountIncorrectPassword)
+// RandomPassword sets password which is unknown to user,
+// so it is not a login method until user resets it.
+func (a *Account) RandomPassword() error {
+	p, err := utils.UniqueString(32)
+	if err != nil {
+		return fmt.Errorf("utils.UniqueString: %w", apperrors.ErrAccountPasswordNotGenerated)
 	}
 
+	a.Password = p
+	a.PasswordGenerated = true
+
 	return nil
 }
 
-func (a *Account) RandomPassword() {
-	a.Password = utils.RandomSpecialString(16)
+// Restorable reports whether archived account can be restored within grace period.
+func (a *Account) Restorable(grace time.Duration) bool {
+	return a.Archive && a.ArchivedAt != nil && time.Since(*a.ArchivedAt) < grace
 }
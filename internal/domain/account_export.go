@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// AccountExport represents all personal data stored about account.
+type AccountExport struct {
+	Account    Account       `json:"account"`
+	Sessions   []Session     `json:"sessions"`
+	Identities []Identity    `json:"identities"`
+	Audit      []AuditRecord `json:"audit"`
+	ExportedAt time.Time     `json:"exportedAt"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+// AccountRestore represents single-use token to restore archived account,
+// only hash of the token is stored.
+type AccountRestore struct {
+	AccountID string
+	Token     string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func NewAccountRestore(aid string, ttl time.Duration) (AccountRestore, error) {
+	t, err := utils.UniqueString(64)
+	if err != nil {
+		return AccountRestore{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrAccountRestoreTokenNotCreated)
+	}
+
+	now := time.Now()
+
+	return AccountRestore{
+		AccountID: aid,
+		Token:     t,
+		TokenHash: utils.SHA256(t),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+func (r *AccountRestore) Expired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
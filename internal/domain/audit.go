@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Audit actions
+const (
+	AuditAccountArchived     = "account.archived"
+	AuditAccountRestored     = "account.restored"
+	AuditAccountErased       = "account.erased"
+	AuditAccountExported     = "account.exported"
+	AuditPasswordChanged     = "password.changed"
+	AuditPasswordReset       = "password.reset"
+	AuditEmailChanged        = "email.changed"
+	AuditEmailChangeReverted = "email.change_reverted"
+	AuditRecoveryCodeUsed    = "mfa.recovery_code_used"
+	AuditRecoveryCodesIssued = "mfa.recovery_codes_issued"
+
+	AuditWebAuthnCredentialAdded   = "webauthn.credential_added"
+	AuditWebAuthnCredentialRemoved = "webauthn.credential_removed"
+
+	AuditIdentityLinked   = "identity.linked"
+	AuditIdentityUnlinked = "identity.unlinked"
+)
+
+// AuditRecord represents action performed on account.
+type AuditRecord struct {
+	ID        string            `json:"id"`
+	AccountID string            `json:"accountId"`
+	Action    string            `json:"action"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+// EmailChange represents pending change of account email.
+// Token is sent to new email to confirm the change, revert token is sent to old email
+// to cancel the change or to restore old email if change is already confirmed.
+// Only hashes of tokens are stored.
+type EmailChange struct {
+	AccountID       string
+	OldEmail        string
+	NewEmail        string
+	Token           string
+	TokenHash       string
+	RevertToken     string
+	RevertTokenHash string
+	Confirmed       bool
+	ExpiresAt       time.Time
+	RevertExpiresAt time.Time
+	CreatedAt       time.Time
+}
+
+func NewEmailChange(aid, oldEmail, newEmail string, ttl, revertTTL time.Duration) (EmailChange, error) {
+	t, err := utils.UniqueString(64)
+	if err != nil {
+		return EmailChange{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrEmailChangeTokenNotCreated)
+	}
+
+	rt, err := utils.UniqueString(64)
+	if err != nil {
+		return EmailChange{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrEmailChangeTokenNotCreated)
+	}
+
+	now := time.Now()
+
+	return EmailChange{
+		AccountID:       aid,
+		OldEmail:        oldEmail,
+		NewEmail:        newEmail,
+		Token:           t,
+		TokenHash:       utils.SHA256(t),
+		RevertToken:     rt,
+		RevertTokenHash: utils.SHA256(rt),
+		ExpiresAt:       now.Add(ttl),
+		RevertExpiresAt: now.Add(revertTTL),
+		CreatedAt:       now,
+	}, nil
+}
+
+func (ec *EmailChange) Expired() bool {
+	return time.Now().After(ec.ExpiresAt)
+}
+
+func (ec *EmailChange) RevertExpired() bool {
+	return time.Now().After(ec.RevertExpiresAt)
+}
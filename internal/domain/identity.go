@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// Identity represents user of social provider linked to account,
+// provider user id is immutable unlike email and username of the user.
+type Identity struct {
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"providerUserId"`
+	AccountID      string    `json:"-"`
+	Email          string    `json:"email,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
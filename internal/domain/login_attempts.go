@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// LoginAttempts represents failed login attempts made with the same login or from the same ip.
+type LoginAttempts struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+func (a *LoginAttempts) Locked() bool {
+	return time.Now().Before(a.LockedUntil)
+}
+
+// RetryAfter returns duration until lock is released.
+func (a *LoginAttempts) RetryAfter() time.Duration {
+	return time.Until(a.LockedUntil)
+}
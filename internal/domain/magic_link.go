@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+// MagicLink represents single-use passwordless login link,
+// token of the link is signed and bound to the browser which requested it
+// by browser token stored in cookie. Only hashes of the tokens are stored.
+type MagicLink struct {
+	AccountID        string
+	Token            string
+	TokenHash        string
+	BrowserTokenHash string
+	UserAgent        string
+	IP               string
+	ExpiresAt        time.Time
+	CreatedAt        time.Time
+}
+
+func NewMagicLink(aid, browserToken, userAgent, ip, signingKey string, ttl time.Duration) (MagicLink, error) {
+	t, err := utils.UniqueString(64)
+	if err != nil {
+		return MagicLink{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrMagicLinkNotCreated)
+	}
+
+	t = t + "." + utils.HMACSHA256(signingKey, t)
+	now := time.Now()
+
+	return MagicLink{
+		AccountID:        aid,
+		Token:            t,
+		TokenHash:        utils.SHA256(t),
+		BrowserTokenHash: utils.SHA256(browserToken),
+		UserAgent:        userAgent,
+		IP:               ip,
+		ExpiresAt:        now.Add(ttl),
+		CreatedAt:        now,
+	}, nil
+}
+
+// MagicLinkTokenValid reports whether magic link token is signed with signing key.
+func MagicLinkTokenValid(token, signingKey string) bool {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return false
+	}
+
+	sig := utils.HMACSHA256(signingKey, token[:i])
+
+	return subtle.ConstantTimeCompare([]byte(token[i+1:]), []byte(sig)) == 1
+}
+
+func (l *MagicLink) Expired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
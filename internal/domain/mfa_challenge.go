@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+// Second factor methods which can be used to complete mfa challenge
+const (
+	MFAMethodTOTP  = "totp"
+	MFAMethodEmail = "email"
+)
+
+const emailCodeLen = 6
+
+// MFAChallenge represents pending login which must be completed with second factor
+// from the same device to create session. Email code is stored hashed
+// and shares attempts counter with other methods. Archived account is restored
+// only when challenge is completed.
+type MFAChallenge struct {
+	ID                 string    `json:"id" bson:"_id"`
+	AccountID          string    `json:"-" bson:"accountId"`
+	Provider           string    `json:"-" bson:"provider"`
+	Restore            bool      `json:"-" bson:"restore"`
+	Methods            []string  `json:"methods" bson:"methods"`
+	UserAgent          string    `json:"-" bson:"userAgent"`
+	IP                 string    `json:"-" bson:"ip"`
+	Attempts           int       `json:"-" bson:"attempts"`
+	EmailCodeHash      string    `json:"-" bson:"emailCodeHash"`
+	EmailCodeSentAt    time.Time `json:"-" bson:"emailCodeSentAt"`
+	EmailCodeExpiresAt time.Time `json:"-" bson:"emailCodeExpiresAt"`
+	ExpiresAt          time.Time `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt          time.Time `json:"-" bson:"createdAt"`
+}
+
+func NewMFAChallenge(aid, provider string, restore bool, methods []string, userAgent, ip string,
+	ttl time.Duration) (MFAChallenge, error) {
+
+	id, err := utils.UniqueString(32)
+	if err != nil {
+		return MFAChallenge{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrMFAChallengeNotCreated)
+	}
+
+	now := time.Now()
+
+	return MFAChallenge{
+		ID:        id,
+		AccountID: aid,
+		Provider:  provider,
+		Restore:   restore,
+		Methods:   methods,
+		UserAgent: userAgent,
+		IP:        ip,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+func (c *MFAChallenge) Expired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// Allows reports whether challenge can be completed with given method.
+func (c *MFAChallenge) Allows(method string) bool {
+	for _, m := range c.Methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewEmailCode generates 6 digit code which is sent to account email to complete challenge,
+// returns the code and its hash.
+func (c *MFAChallenge) NewEmailCode() (string, string, error) {
+	code, err := utils.UniqueDigits(emailCodeLen)
+	if err != nil {
+		return "", "", fmt.Errorf("utils.UniqueDigits: %w", apperrors.ErrMFAChallengeNotCreated)
+	}
+
+	return code, c.EmailCodeHashOf(code), nil
+}
+
+// EmailCodeHashOf returns hash of email code salted with challenge id.
+func (c *MFAChallenge) EmailCodeHashOf(code string) string {
+	return utils.SHA256(c.ID + code)
+}
+
+// EmailCodeExpired reports whether email code is expired or was not sent.
+func (c *MFAChallenge) EmailCodeExpired() bool {
+	return c.EmailCodeHash == "" || time.Now().After(c.EmailCodeExpiresAt)
+}
+
+// EmailCodeValid reports whether code matches email code of the challenge.
+func (c *MFAChallenge) EmailCodeValid(code string) bool {
+	return subtle.ConstantTimeCompare([]byte(c.EmailCodeHashOf(code)), []byte(c.EmailCodeHash)) == 1
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+const codeVerifierLen = 64
+
+// OAuthState represents pending social login or linking of identity to account which must be completed
+// by callback from the same browser before it expires. Only hashes of state and browser token are stored,
+// code verifier is sent to provider with authorization code to prove that login is started by the service (PKCE).
+// Account id is empty for login since account is identified by identity.
+type OAuthState struct {
+	State            string    `json:"-" bson:"-"`
+	StateHash        string    `json:"-" bson:"_id"`
+	AccountID        string    `json:"-" bson:"accountId"`
+	Provider         string    `json:"-" bson:"provider"`
+	BrowserTokenHash string    `json:"-" bson:"browserTokenHash"`
+	CodeVerifier     string    `json:"-" bson:"codeVerifier"`
+	ExpiresAt        time.Time `json:"-" bson:"expiresAt"`
+	CreatedAt        time.Time `json:"-" bson:"createdAt"`
+}
+
+func NewOAuthState(aid, provider, browserToken string, ttl time.Duration) (OAuthState, error) {
+	state, err := utils.UniqueString(32)
+	if err != nil {
+		return OAuthState{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrOAuthStateNotCreated)
+	}
+
+	verifier, err := utils.UniqueString(codeVerifierLen)
+	if err != nil {
+		return OAuthState{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrOAuthStateNotCreated)
+	}
+
+	now := time.Now()
+
+	return OAuthState{
+		State:            state,
+		StateHash:        utils.SHA256(state),
+		AccountID:        aid,
+		Provider:         provider,
+		BrowserTokenHash: utils.SHA256(browserToken),
+		CodeVerifier:     verifier,
+		ExpiresAt:        now.Add(ttl),
+		CreatedAt:        now,
+	}, nil
+}
+
+// CodeChallenge returns S256 PKCE code challenge of code verifier.
+func (s *OAuthState) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(s.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s *OAuthState) Expired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
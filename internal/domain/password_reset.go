@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+// PasswordReset represents single-use password reset token,
+// only hash of the token is stored.
+type PasswordReset struct {
+	AccountID string
+	Token     string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func NewPasswordReset(aid string, ttl time.Duration) (PasswordReset, error) {
+	t, err := utils.UniqueString(64)
+	if err != nil {
+		return PasswordReset{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrPasswordResetTokenNotCreated)
+	}
+
+	now := time.Now()
+
+	return PasswordReset{
+		AccountID: aid,
+		Token:     t,
+		TokenHash: utils.SHA256(t),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+func (r *PasswordReset) Expired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
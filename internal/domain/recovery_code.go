@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+const recoveryCodeLen = 10
+
+// RecoveryCode represents single-use code which can be used instead of second factor,
+// only hash of the code is stored.
+type RecoveryCode struct {
+	Code     string
+	CodeHash string
+}
+
+// NewRecoveryCodes generates n recovery codes formatted as xxxxx-xxxxx.
+func NewRecoveryCodes(n int) ([]RecoveryCode, error) {
+	codes := make([]RecoveryCode, n)
+
+	for i := range codes {
+		c, err := utils.UniqueString(recoveryCodeLen)
+		if err != nil {
+			return nil, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrRecoveryCodesNotCreated)
+		}
+
+		c = strings.ToLower(c)
+
+		codes[i] = RecoveryCode{
+			Code:     c[:recoveryCodeLen/2] + "-" + c[recoveryCodeLen/2:],
+			CodeHash: RecoveryCodeHash(c),
+		}
+	}
+
+	return codes, nil
+}
+
+// RecoveryCodeHash returns hash of recovery code ignoring its case and separators.
+func RecoveryCodeHash(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.SHA256(code)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// TOTP represents account time-based one-time password second factor,
+// secret is stored encrypted.
+type TOTP struct {
+	AccountID       string
+	Secret          string
+	EncryptedSecret []byte
+	Confirmed       bool
+	LastUsedStep    int64
+	CreatedAt       time.Time
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+)
+
+// Verification represents account email verification code,
+// only hash of the code is stored.
+type Verification struct {
+	AccountID string
+	Code      string
+	CodeHash  string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func NewVerification(aid string, ttl time.Duration) (Verification, error) {
+	code, err := utils.UniqueString(32)
+	if err != nil {
+		return Verification{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrVerificationCodeNotCreated)
+	}
+
+	now := time.Now()
+
+	return Verification{
+		AccountID: aid,
+		Code:      code,
+		CodeHash:  utils.SHA256(code),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+func (v *Verification) Expired() bool {
+	return time.Now().After(v.ExpiresAt)
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/utils"
+	"github.com/ysomad/go-auth-service/pkg/webauthn"
+)
+
+// WebAuthn ceremony types
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+)
+
+// WebAuthnCredential represents public key credential registered by authenticator of account,
+// id is base64url encoded credential id generated by authenticator.
+type WebAuthnCredential struct {
+	ID         string     `json:"id"`
+	AccountID  string     `json:"-"`
+	Name       string     `json:"name"`
+	PublicKey  []byte     `json:"-"`
+	SignCount  uint32     `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// WebAuthnCeremony represents pending registration or login ceremony
+// which must be completed from the same device before it expires.
+// Account id is empty for login ceremony since account is identified by credential.
+type WebAuthnCeremony struct {
+	ID        string    `json:"id" bson:"_id"`
+	AccountID string    `json:"-" bson:"accountId"`
+	Type      string    `json:"-" bson:"type"`
+	Challenge string    `json:"challenge" bson:"challenge"`
+	UserAgent string    `json:"-" bson:"userAgent"`
+	IP        string    `json:"-" bson:"ip"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt time.Time `json:"-" bson:"createdAt"`
+}
+
+func NewWebAuthnCeremony(aid, ceremonyType, userAgent, ip string, ttl time.Duration) (WebAuthnCeremony, error) {
+	id, err := utils.UniqueString(32)
+	if err != nil {
+		return WebAuthnCeremony{}, fmt.Errorf("utils.UniqueString: %w", apperrors.ErrWebAuthnCeremonyNotCreated)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return WebAuthnCeremony{}, fmt.Errorf("webauthn.NewChallenge: %w", apperrors.ErrWebAuthnCeremonyNotCreated)
+	}
+
+	now := time.Now()
+
+	return WebAuthnCeremony{
+		ID:        id,
+		AccountID: aid,
+		Type:      ceremonyType,
+		Challenge: challenge,
+		UserAgent: userAgent,
+		IP:        ip,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+func (c *WebAuthnCeremony) Expired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -13,6 +15,7 @@ import (
 
 	"github.com/ysomad/go-auth-service/pkg/apperrors"
 	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/ratelimit"
 	"github.com/ysomad/go-auth-service/pkg/validation"
 )
 
@@ -25,7 +28,7 @@ type accountHandler struct {
 }
 
 func newAccountHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin, cfg *config.Config,
-	acc service.Account, s service.Session, auth service.Auth) {
+	newLimiter ratelimit.NewFunc, acc service.Account, s service.Session, auth service.Auth) {
 
 	h := &accountHandler{l, v, cfg, acc, s}
 
@@ -36,12 +39,30 @@ func newAccountHandler(handler *gin.RouterGroup, l logger.Interface, v validatio
 			secure := authenticated.Group("/", tokenMiddleware(l, auth))
 			{
 				secure.DELETE("", h.archive)
+				secure.PUT("password", h.changePassword)
+				secure.POST("email", h.requestEmailChange)
+				secure.GET("export", h.export)
 			}
 
 			authenticated.GET("", h.get)
+			authenticated.PATCH("", h.update)
 		}
 
-		g.POST("", h.create)
+		g.POST("", policyRateLimitMiddleware(l, cfg, newLimiter, "account_create"), h.create)
+		g.POST("verify", h.verify)
+		g.POST("verify/resend", h.resendVerification)
+		g.POST("password/reset", h.requestPasswordReset)
+		g.POST("password/reset/confirm", h.resetPassword)
+		g.POST("email/confirm", h.confirmEmailChange)
+		g.POST("email/revert", h.revertEmailChange)
+		g.POST("restore", h.requestRestore)
+		g.POST("restore/confirm", h.restore)
+		g.GET(
+			"availability",
+			rateLimitMiddleware(l, newLimiter("availability", cfg.Availability.RateLimit, cfg.Availability.RatePeriod), "ip"),
+			csrfMiddleware(l, cfg),
+			h.availability,
+		)
 	}
 }
 
@@ -66,6 +87,10 @@ func (h *accountHandler) create(c *gin.Context) {
 	if err != nil {
 		h.log.Error(fmt.Errorf("http - v1 - account - create: %w", err))
 
+		if abortWithPasswordPolicy(c, "password", err) {
+			return
+		}
+
 		if errors.Is(err, apperrors.ErrAccountAlreadyExist) {
 			abortWithError(c, http.StatusConflict, apperrors.ErrAccountAlreadyExist)
 			return
@@ -126,5 +151,447 @@ func (h *accountHandler) get(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(acc.Version))
 	c.JSON(http.StatusOK, acc)
 }
+
+type accountVerifyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (h *accountHandler) verify(c *gin.Context) {
+	var r accountVerifyRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.Verify(c.Request.Context(), r.Code); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - verify: %w", err))
+
+		if errors.Is(err, apperrors.ErrVerificationCodeNotFound) ||
+			errors.Is(err, apperrors.ErrVerificationCodeExpired) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrVerificationCodeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type accountResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email,lte=255"`
+}
+
+// resendVerification always responds with 202 on valid request
+// to not reveal whether account with given email exist.
+func (h *accountHandler) resendVerification(c *gin.Context) {
+	var r accountResendVerificationRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.ResendVerification(c.Request.Context(), r.Email); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - resendVerification: %w", err))
+
+		if !errors.Is(err, apperrors.ErrAccountNotFound) &&
+			!errors.Is(err, apperrors.ErrAccountAlreadyVerified) &&
+			!errors.Is(err, apperrors.ErrVerificationCooldown) {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type accountPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email,lte=255"`
+}
+
+// requestPasswordReset always responds with 202 on valid request
+// to not reveal whether account with given email exist.
+func (h *accountHandler) requestPasswordReset(c *gin.Context) {
+	var r accountPasswordResetRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	// errors are only logged, status must not depend on whether reset is requested for existing account
+	if err := h.accountService.RequestPasswordReset(c.Request.Context(), r.Email); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - requestPasswordReset: %w", err))
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type accountPasswordResetConfirmRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,gte=8,lte=64"`
+}
+
+func (h *accountHandler) resetPassword(c *gin.Context) {
+	var r accountPasswordResetConfirmRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.ResetPassword(c.Request.Context(), r.Token, r.Password); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - resetPassword: %w", err))
+
+		if abortWithPasswordPolicy(c, "password", err) {
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrPasswordResetTokenNotFound) ||
+			errors.Is(err, apperrors.ErrPasswordResetTokenExpired) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrPasswordResetTokenNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type accountChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,gte=8,lte=64,nefield=OldPassword"`
+}
+
+func (h *accountHandler) changePassword(c *gin.Context) {
+	var r accountChangePasswordRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - changePassword - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	sid, err := sessionID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - changePassword - sessionID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.accountService.ChangePassword(c.Request.Context(), aid, sid, r.OldPassword, r.NewPassword); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - changePassword: %w", err))
+
+		if abortWithPasswordPolicy(c, "newpassword", err) {
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAccountIncorrectPassword) {
+			abortWithError(c, http.StatusForbidden, apperrors.ErrAccountIncorrectPassword)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type accountEmailChangeRequest struct {
+	Email string `json:"email" binding:"required,email,lte=255"`
+}
+
+func (h *accountHandler) requestEmailChange(c *gin.Context) {
+	var r accountEmailChangeRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - requestEmailChange - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.accountService.RequestEmailChange(c.Request.Context(), aid, r.Email); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - requestEmailChange: %w", err))
+
+		if errors.Is(err, apperrors.ErrAccountAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrAccountAlreadyExist)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrEmailChangeSameEmail) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrEmailChangeSameEmail)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type accountEmailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *accountHandler) confirmEmailChange(c *gin.Context) {
+	var r accountEmailChangeTokenRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.ConfirmEmailChange(c.Request.Context(), r.Token); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - confirmEmailChange: %w", err))
+
+		if errors.Is(err, apperrors.ErrAccountAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrAccountAlreadyExist)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrEmailChangeNotFound) ||
+			errors.Is(err, apperrors.ErrEmailChangeExpired) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrEmailChangeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *accountHandler) revertEmailChange(c *gin.Context) {
+	var r accountEmailChangeTokenRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.RevertEmailChange(c.Request.Context(), r.Token); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - revertEmailChange: %w", err))
+
+		if errors.Is(err, apperrors.ErrAccountAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrAccountAlreadyExist)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrEmailChangeNotFound) ||
+			errors.Is(err, apperrors.ErrEmailChangeExpired) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrEmailChangeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type accountRestoreRequest struct {
+	Email string `json:"email" binding:"required,email,lte=255"`
+}
+
+// requestRestore always responds with 202 on valid request
+// to not reveal whether archived account with given email exist.
+func (h *accountHandler) requestRestore(c *gin.Context) {
+	var r accountRestoreRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.RequestRestore(c.Request.Context(), r.Email); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - requestRestore: %w", err))
+
+		if !errors.Is(err, apperrors.ErrAccountNotFound) &&
+			!errors.Is(err, apperrors.ErrAccountRestorePeriodExpired) {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type accountRestoreConfirmRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *accountHandler) restore(c *gin.Context) {
+	var r accountRestoreConfirmRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	if err := h.accountService.Restore(c.Request.Context(), r.Token); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - restore: %w", err))
+
+		if errors.Is(err, apperrors.ErrAccountRestoreTokenNotFound) ||
+			errors.Is(err, apperrors.ErrAccountRestoreTokenExpired) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrAccountRestoreTokenNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAccountRestorePeriodExpired) {
+			abortWithError(c, http.StatusGone, apperrors.ErrAccountRestorePeriodExpired)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *accountHandler) export(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - export - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	e, err := h.accountService.Export(c.Request.Context(), aid)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - export: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"account-%s.json\"", aid))
+	c.JSON(http.StatusOK, e)
+}
+
+type accountUpdateRequest struct {
+	Username string `json:"username" binding:"required,alphanum,gte=4,lte=16"`
+}
+
+// update updates account profile, current account version must be provided in If-Match header
+// to prevent overwriting changes made concurrently.
+func (h *accountHandler) update(c *gin.Context) {
+	var r accountUpdateRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - update - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	v, err := ifMatchVersion(c)
+	if err != nil {
+		abortWithError(c, http.StatusPreconditionRequired, apperrors.ErrAccountVersionRequired)
+		return
+	}
+
+	acc, err := h.accountService.Update(
+		c.Request.Context(),
+		domain.Account{ID: aid, Username: r.Username, Version: v},
+	)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - update: %w", err))
+
+		if errors.Is(err, apperrors.ErrAccountAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrAccountAlreadyExist)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAccountVersionConflict) {
+			abortWithError(c, http.StatusPreconditionFailed, apperrors.ErrAccountVersionConflict)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", etag(acc.Version))
+	c.JSON(http.StatusOK, acc)
+}
+
+// etag returns strong entity tag of given version.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion returns version from If-Match request header.
+func ifMatchVersion(c *gin.Context) (int, error) {
+	h := strings.TrimPrefix(c.GetHeader("If-Match"), "W/")
+
+	v, err := strconv.Unquote(h)
+	if err != nil {
+		v = h
+	}
+
+	return strconv.Atoi(v)
+}
+
+type accountAvailabilityRequest struct {
+	Username string `form:"username" binding:"required_without=Email,omitempty,alphanum,gte=4,lte=16"`
+	Email    string `form:"email" binding:"required_without=Username,omitempty,email,lte=255"`
+}
+
+type accountAvailabilityResponse struct {
+	Username *bool `json:"username,omitempty"`
+	Email    *bool `json:"email,omitempty"`
+}
+
+// availability reports whether username and email provided in query are not taken,
+// only fields present in query are checked.
+func (h *accountHandler) availability(c *gin.Context) {
+	var r accountAvailabilityRequest
+
+	if err := c.ShouldBindQuery(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	a, err := h.accountService.CheckAvailability(c.Request.Context(), r.Username, r.Email)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - account - availability: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, accountAvailabilityResponse{a.Username, a.Email})
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/ysomad/go-auth-service/pkg/apperrors"
 	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/ratelimit"
+	"github.com/ysomad/go-auth-service/pkg/utils"
 	"github.com/ysomad/go-auth-service/pkg/validation"
 )
 
@@ -23,19 +25,42 @@ type authHandler struct {
 	socialAuthService service.SocialAuth
 }
 
-func newAuthHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin, cfg *config.Config, s service.Session,
-	a service.Auth, sa service.SocialAuth) {
+func newAuthHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin, cfg *config.Config,
+	newLimiter ratelimit.NewFunc, s service.Session, a service.Auth, sa service.SocialAuth) {
 
 	h := &authHandler{l, v, cfg, a, sa}
 
 	g := handler.Group("/auth")
 	{
+		g.GET("csrf", setCSRFTokenMiddleware(l, cfg), h.csrf)
 		g.POST("login", h.login).Use(setCSRFTokenMiddleware(l, cfg))
 
+		magic := g.Group("/magic-link")
+		{
+			magic.POST(
+				"",
+				rateLimitMiddleware(l, newLimiter("magic_link", cfg.MagicLink.RateLimit, cfg.MagicLink.RatePeriod), "ip"),
+				h.requestMagicLink,
+			)
+			magic.POST("login", h.magicLinkLogin)
+		}
+
 		social := g.Group("/social", setCSRFTokenMiddleware(l, cfg))
 		{
-			social.GET("", h.socialAuthorizationURL)
-			social.POST("github", h.githubLogin).Use(csrfMiddleware(l, cfg))
+			social.GET("", policyRateLimitMiddleware(l, cfg, newLimiter, "social_url"), h.socialAuthorizationURL)
+			social.POST(":provider", csrfMiddleware(l, cfg), h.socialLogin)
+		}
+
+		identities := g.Group("/identities", sessionMiddleware(l, s))
+		{
+			secure := identities.Group("/", tokenMiddleware(l, a))
+			{
+				secure.GET(":provider/url", h.identityLinkURL)
+				secure.DELETE(":provider", h.unlinkIdentity)
+			}
+
+			identities.GET("", h.getIdentities)
+			identities.POST(":provider", h.linkIdentity)
 		}
 
 		protected := g.Group("/", csrfMiddleware(l, cfg), sessionMiddleware(l, s))
@@ -47,8 +72,11 @@ func newAuthHandler(handler *gin.RouterGroup, l logger.Interface, v validation.G
 	}
 }
 
+// loginRequest accepts account email or username as login,
+// email field is kept for backward compatibility.
 type loginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Login    string `json:"login" binding:"required_without=Email,lte=255"`
+	Email    string `json:"email" binding:"omitempty,email"`
 	Password string `json:"password" binding:"required"`
 }
 
@@ -61,9 +89,14 @@ func (h *authHandler) login(c *gin.Context) {
 		return
 	}
 
-	s, err := h.authService.EmailLogin(
+	login := r.Login
+	if login == "" {
+		login = r.Email
+	}
+
+	res, err := h.authService.Login(
 		c.Request.Context(),
-		r.Email,
+		login,
 		r.Password,
 		service.Device{
 			IP:        c.ClientIP(),
@@ -73,9 +106,18 @@ func (h *authHandler) login(c *gin.Context) {
 	if err != nil {
 		h.log.Error(fmt.Errorf("http - v1 - auth - login: %w", err))
 
+		if abortWithLockout(c, err) {
+			return
+		}
+
 		if errors.Is(err, apperrors.ErrAccountIncorrectPassword) ||
 			errors.Is(err, apperrors.ErrAccountNotFound) {
-			abortWithError(c, http.StatusUnauthorized, apperrors.ErrAccountIncorrectEmailOrPassword)
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrAccountIncorrectLoginOrPassword)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAccountNotVerified) {
+			abortWithError(c, http.StatusForbidden, apperrors.ErrAccountNotVerified)
 			return
 		}
 
@@ -83,10 +125,136 @@ func (h *authHandler) login(c *gin.Context) {
 		return
 	}
 
+	if res.Challenge != nil {
+		c.JSON(http.StatusAccepted, mfaChallengeResponse{res.Challenge.ID, res.Challenge.Methods, res.Challenge.ExpiresAt})
+		return
+	}
+
 	c.SetCookie(
 		h.cfg.Session.CookieKey,
-		s.ID,
-		s.TTL,
+		res.Session.ID,
+		res.Session.TTL,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		h.cfg.Session.CookieHTTPOnly,
+	)
+	c.Status(http.StatusOK)
+}
+
+type magicLinkRequest struct {
+	Email string `json:"email" binding:"required,email,lte=255"`
+}
+
+// requestMagicLink sends login link to account email and binds it to the browser
+// with random token stored in cookie, response does not reveal whether account exists.
+func (h *authHandler) requestMagicLink(c *gin.Context) {
+	var r magicLinkRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	bt, err := utils.UniqueString(32)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - requestMagicLink - utils.UniqueString: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.authService.RequestMagicLink(
+		c.Request.Context(),
+		r.Email,
+		bt,
+		service.Device{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.Header.Get("User-Agent"),
+		},
+	)
+	// errors are only logged, status must not depend on whether link is requested for existing account
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - requestMagicLink: %w", err))
+	}
+
+	c.SetCookie(
+		h.cfg.MagicLink.CookieKey,
+		bt,
+		int(h.cfg.MagicLink.TokenTTL.Seconds()),
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		true,
+	)
+	c.Status(http.StatusAccepted)
+}
+
+type magicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *authHandler) magicLinkLogin(c *gin.Context) {
+	var r magicLinkLoginRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	bt, err := c.Cookie(h.cfg.MagicLink.CookieKey)
+	if err != nil || bt == "" {
+		h.log.Error(fmt.Errorf("http - v1 - auth - magicLinkLogin - c.Cookie: %w", apperrors.ErrMagicLinkNotFound))
+		abortWithError(c, http.StatusUnauthorized, apperrors.ErrMagicLinkNotFound)
+		return
+	}
+
+	res, err := h.authService.MagicLinkLogin(
+		c.Request.Context(),
+		r.Token,
+		bt,
+		service.Device{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.Header.Get("User-Agent"),
+		},
+	)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - magicLinkLogin: %w", err))
+
+		if errors.Is(err, apperrors.ErrMagicLinkExpired) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMagicLinkExpired)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMagicLinkNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMagicLinkNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.MagicLink.CookieKey,
+		"",
+		-1,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		true,
+	)
+
+	if res.Challenge != nil {
+		c.JSON(http.StatusAccepted, mfaChallengeResponse{res.Challenge.ID, res.Challenge.Methods, res.Challenge.ExpiresAt})
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.Session.CookieKey,
+		res.Session.ID,
+		res.Session.TTL,
 		apiPath,
 		h.cfg.Session.CookieDomain,
 		h.cfg.Session.CookieSecure,
@@ -121,6 +289,12 @@ func (h *authHandler) logout(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// csrf issues new CSRF token in headers and cookies,
+// it must be requested before making requests protected from CSRF, e.g. signup form checks.
+func (h *authHandler) csrf(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
 type tokenRequest struct {
 	Password string `json:"password" binding:"required"`
 }
@@ -145,10 +319,17 @@ func (h *authHandler) token(c *gin.Context) {
 		return
 	}
 
-	t, err := h.authService.NewAccessToken(c.Request.Context(), aid, r.Password)
+	t, err := h.authService.NewAccessToken(c.Request.Context(), aid, r.Password, service.Device{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.Header.Get("User-Agent"),
+	})
 	if err != nil {
 		h.log.Error(fmt.Errorf("http - v1 - auth - token: %w", err))
 
+		if abortWithLockout(c, err) {
+			return
+		}
+
 		if errors.Is(err, apperrors.ErrAccountIncorrectPassword) {
 			c.AbortWithStatus(http.StatusForbidden)
 			return
@@ -172,48 +353,141 @@ func (h *authHandler) socialAuthorizationURL(c *gin.Context) {
 		return
 	}
 
-	uri, err := h.socialAuthService.AuthorizationURL(c.Request.Context(), provider)
+	// browser token is reused so authorization urls requested in several tabs stay valid
+	bt, err := c.Cookie(h.cfg.SocialAuth.StateCookieKey)
+	if err != nil || bt == "" {
+		bt, err = utils.UniqueString(32)
+		if err != nil {
+			h.log.Error(fmt.Errorf("http - v1 - auth - socialAuthorizationURL - utils.UniqueString: %w", err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	uri, err := h.socialAuthService.AuthorizationURL(c.Request.Context(), provider, bt)
 	if err != nil {
-		h.log.Error(fmt.Errorf("http - v1 - auth - getOAuthURI: %w", err))
+		h.log.Error(fmt.Errorf("http - v1 - auth - socialAuthorizationURL: %w", err))
+
+		if errors.Is(err, apperrors.ErrAuthProviderNotSupported) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrAuthProviderNotSupported)
+			return
+		}
+
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
+	c.SetCookie(
+		h.cfg.SocialAuth.StateCookieKey,
+		bt,
+		int(h.cfg.SocialAuth.StateTTL.Seconds()),
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		true,
+	)
 	c.JSON(http.StatusOK, getOAuthURIResponse{uri.String()})
 }
 
-func (h *authHandler) githubLogin(c *gin.Context) {
+func (h *authHandler) socialLogin(c *gin.Context) {
 	code, found := c.GetQuery("code")
 	if !found || code == "" {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 
-	// TODO: CSRF protection
 	state, found := c.GetQuery("state")
 	if !found || state == "" {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 
-	s, err := h.socialAuthService.GitHubLogin(
+	bt, err := c.Cookie(h.cfg.SocialAuth.StateCookieKey)
+	if err != nil || bt == "" {
+		h.log.Error(fmt.Errorf("http - v1 - auth - socialLogin - c.Cookie: %w", apperrors.ErrOAuthStateNotFound))
+		abortWithError(c, http.StatusUnauthorized, apperrors.ErrOAuthStateNotFound)
+		return
+	}
+
+	res, err := h.socialAuthService.Login(
 		c.Request.Context(),
+		c.Param("provider"),
 		code,
+		state,
+		bt,
 		service.Device{
 			UserAgent: c.Request.Header.Get("User-Agent"),
 			IP:        c.ClientIP(),
 		},
 	)
 	if err != nil {
-		h.log.Error(fmt.Errorf("http - v1 - auth - githubCallback: %w", err))
+		h.log.Error(fmt.Errorf("http - v1 - auth - socialLogin: %w", err))
+
+		if errors.Is(err, apperrors.ErrAuthProviderNotSupported) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrAuthProviderNotSupported)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrOAuthStateNotFound) ||
+			errors.Is(err, apperrors.ErrOAuthStateExpired) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrOAuthStateNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAuthIDTokenNotReceived) ||
+			errors.Is(err, apperrors.ErrAuthIDTokenInvalid) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrAuthIDTokenInvalid)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAuthEmailNotVerified) {
+			abortWithError(c, http.StatusForbidden, apperrors.ErrAuthEmailNotVerified)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAuthEmailNotReceived) {
+			abortWithError(c, http.StatusForbidden, apperrors.ErrAuthEmailNotReceived)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAuthAccountNotVerified) {
+			abortWithError(c, http.StatusForbidden, apperrors.ErrAuthAccountNotVerified)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAccountNotVerified) {
+			abortWithError(c, http.StatusForbidden, apperrors.ErrAccountNotVerified)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrIdentityAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrIdentityAlreadyExist)
+			return
+		}
+
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
 
+	c.SetCookie(
+		h.cfg.SocialAuth.StateCookieKey,
+		"",
+		-1,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		true,
+	)
+
+	if res.Challenge != nil {
+		c.JSON(http.StatusAccepted, mfaChallengeResponse{res.Challenge.ID, res.Challenge.Methods, res.Challenge.ExpiresAt})
+		return
+	}
+
 	c.SetCookie(
 		h.cfg.Session.CookieKey,
-		s.ID,
-		s.TTL,
+		res.Session.ID,
+		res.Session.TTL,
 		apiPath,
 		h.cfg.Session.CookieDomain,
 		h.cfg.Session.CookieSecure,
@@ -221,3 +495,161 @@ func (h *authHandler) githubLogin(c *gin.Context) {
 	)
 	c.Status(http.StatusOK)
 }
+
+func (h *authHandler) getIdentities(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - getIdentities - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.socialAuthService.Identities(c.Request.Context(), aid)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - getIdentities: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+func (h *authHandler) identityLinkURL(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - identityLinkURL - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	bt, err := c.Cookie(h.cfg.SocialAuth.StateCookieKey)
+	if err != nil || bt == "" {
+		bt, err = utils.UniqueString(32)
+		if err != nil {
+			h.log.Error(fmt.Errorf("http - v1 - auth - identityLinkURL - utils.UniqueString: %w", err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	uri, err := h.socialAuthService.LinkURL(c.Request.Context(), aid, c.Param("provider"), bt)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - identityLinkURL: %w", err))
+
+		if errors.Is(err, apperrors.ErrAuthProviderNotSupported) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrAuthProviderNotSupported)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.SocialAuth.StateCookieKey,
+		bt,
+		int(h.cfg.SocialAuth.StateTTL.Seconds()),
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		true,
+	)
+	c.JSON(http.StatusOK, getOAuthURIResponse{uri.String()})
+}
+
+func (h *authHandler) linkIdentity(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - linkIdentity - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	code, found := c.GetQuery("code")
+	if !found || code == "" {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	state, found := c.GetQuery("state")
+	if !found || state == "" {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	bt, err := c.Cookie(h.cfg.SocialAuth.StateCookieKey)
+	if err != nil || bt == "" {
+		h.log.Error(fmt.Errorf("http - v1 - auth - linkIdentity - c.Cookie: %w", apperrors.ErrOAuthStateNotFound))
+		abortWithError(c, http.StatusUnauthorized, apperrors.ErrOAuthStateNotFound)
+		return
+	}
+
+	i, err := h.socialAuthService.Link(c.Request.Context(), aid, c.Param("provider"), code, state, bt)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - linkIdentity: %w", err))
+
+		if errors.Is(err, apperrors.ErrAuthProviderNotSupported) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrAuthProviderNotSupported)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrOAuthStateNotFound) ||
+			errors.Is(err, apperrors.ErrOAuthStateExpired) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrOAuthStateNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrAuthIDTokenNotReceived) ||
+			errors.Is(err, apperrors.ErrAuthIDTokenInvalid) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrAuthIDTokenInvalid)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrIdentityAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrIdentityAlreadyExist)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.SocialAuth.StateCookieKey,
+		"",
+		-1,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		true,
+	)
+	c.JSON(http.StatusCreated, i)
+}
+
+func (h *authHandler) unlinkIdentity(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - unlinkIdentity - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.socialAuthService.Unlink(c.Request.Context(), aid, c.Param("provider")); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - auth - unlinkIdentity: %w", err))
+
+		if errors.Is(err, apperrors.ErrIdentityNotFound) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrIdentityNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrIdentityLastLoginMethod) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrIdentityLastLoginMethod)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
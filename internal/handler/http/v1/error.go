@@ -1,7 +1,17 @@
 package v1
 
 import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+
+	"github.com/ysomad/go-auth-service/internal/service"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/password"
 )
 
 type errorResponse struct {
@@ -19,3 +29,37 @@ func abortWithError(c *gin.Context, code int, err error) {
 func abortWithValidationError(c *gin.Context, code int, errs map[string]string) {
 	c.AbortWithStatusJSON(code, validationErrorResponse{errs})
 }
+
+// abortWithPasswordPolicy aborts with 400 and failed password policy rules of field
+// keyed by "<field>.<rule>" if err is password policy error, reports whether request is aborted.
+func abortWithPasswordPolicy(c *gin.Context, field string, err error) bool {
+	var pe *password.PolicyError
+
+	if !errors.As(err, &pe) {
+		return false
+	}
+
+	errs := make(map[string]string, len(pe.Failures))
+	for rule, msg := range pe.Failures {
+		errs[field+"."+rule] = msg
+	}
+
+	abortWithValidationError(c, http.StatusBadRequest, errs)
+
+	return true
+}
+
+// abortWithLockout aborts with 429 and Retry-After header if err is lockout error,
+// reports whether request is aborted.
+func abortWithLockout(c *gin.Context, err error) bool {
+	var le *service.LockoutError
+
+	if !errors.As(err, &le) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(le.RetryAfter.Seconds()))))
+	abortWithError(c, http.StatusTooManyRequests, apperrors.ErrTooManyLoginAttempts)
+
+	return true
+}
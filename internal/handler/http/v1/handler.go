@@ -12,6 +12,7 @@ import (
 	"github.com/ysomad/go-auth-service/internal/service"
 
 	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/ratelimit"
 	"github.com/ysomad/go-auth-service/pkg/validation"
 )
 
@@ -22,10 +23,13 @@ func SetupHandlers(
 	l logger.Interface,
 	v validation.Gin,
 	cfg *config.Config,
+	newLimiter ratelimit.NewFunc,
 	acc service.Account,
 	sess service.Session,
 	auth service.Auth,
 	social service.SocialAuth,
+	mfa service.MFA,
+	webAuthn service.WebAuthn,
 ) {
 	// Options
 	handler.Use(gin.Logger())
@@ -44,8 +48,10 @@ func SetupHandlers(
 	// Resource handlers
 	h := handler.Group(apiPath)
 	{
-		newAccountHandler(h, l, v, cfg, acc, sess, auth)
-		newSessionHandler(h, l, v, sess, auth)
-		newAuthHandler(h, l, v, cfg, sess, auth, social)
+		newAccountHandler(h, l, v, cfg, newLimiter, acc, sess, auth)
+		newSessionHandler(h, l, v, cfg, newLimiter, sess, auth)
+		newAuthHandler(h, l, v, cfg, newLimiter, sess, auth, social)
+		newMFAHandler(h, l, v, cfg, mfa, sess, auth)
+		newWebAuthnHandler(h, l, v, cfg, webAuthn, sess, auth)
 	}
 }
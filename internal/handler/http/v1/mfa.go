@@ -0,0 +1,452 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/service"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/validation"
+)
+
+type mfaHandler struct {
+	log logger.Interface
+	validation.Gin
+	cfg        *config.Config
+	mfaService service.MFA
+}
+
+func newMFAHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin, cfg *config.Config,
+	m service.MFA, s service.Session, auth service.Auth) {
+
+	h := &mfaHandler{l, v, cfg, m}
+
+	g := handler.Group("/mfa")
+	{
+		authenticated := g.Group("/", sessionMiddleware(l, s))
+		{
+			secure := authenticated.Group("/", tokenMiddleware(l, auth))
+			{
+				secure.POST("totp", h.enrollTOTP)
+				secure.DELETE("totp", h.disableTOTP)
+				secure.POST("email", h.enableEmailOTP)
+				secure.DELETE("email", h.disableEmailOTP)
+				secure.POST("recovery-codes", h.regenerateRecoveryCodes)
+			}
+
+			authenticated.POST("totp/confirm", h.confirmTOTP)
+		}
+
+		g.POST("challenge/totp", h.verifyTOTP)
+		g.POST("challenge/email/send", h.sendEmailOTP)
+		g.POST("challenge/email", h.verifyEmailOTP)
+		g.POST("challenge/recovery", h.verifyRecoveryCode)
+	}
+}
+
+// mfaChallengeResponse is returned on login instead of session cookie
+// if account has second factor enabled.
+type mfaChallengeResponse struct {
+	ChallengeID string    `json:"challengeId"`
+	Methods     []string  `json:"methods"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type totpEnrollResponse struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (h *mfaHandler) enrollTOTP(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - enrollTOTP - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	e, err := h.mfaService.EnrollTOTP(c.Request.Context(), aid)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - enrollTOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFAAlreadyEnabled) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrMFAAlreadyEnabled)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, totpEnrollResponse{e.Secret, e.URI, e.RecoveryCodes})
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+func (h *mfaHandler) confirmTOTP(c *gin.Context) {
+	var r totpCodeRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - confirmTOTP - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.mfaService.ConfirmTOTP(c.Request.Context(), aid, r.Code); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - confirmTOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrMFAIncorrectCode)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFATOTPNotFound) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrMFATOTPNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAAlreadyEnabled) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrMFAAlreadyEnabled)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *mfaHandler) disableTOTP(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - disableTOTP - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.mfaService.DisableTOTP(c.Request.Context(), aid); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - disableTOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFATOTPNotFound) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrMFATOTPNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type totpVerifyRequest struct {
+	ChallengeID string `json:"challengeId" binding:"required"`
+	Code        string `json:"code" binding:"required,numeric,len=6"`
+}
+
+func (h *mfaHandler) verifyTOTP(c *gin.Context) {
+	var r totpVerifyRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	s, err := h.mfaService.VerifyTOTP(
+		c.Request.Context(),
+		r.ChallengeID,
+		r.Code,
+		service.Device{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.Header.Get("User-Agent"),
+		},
+	)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - verifyTOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAIncorrectCode)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAMethodNotAllowed) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrMFAMethodNotAllowed)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAChallengeNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) ||
+			errors.Is(err, apperrors.ErrMFATOTPNotFound) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAChallengeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.Session.CookieKey,
+		s.ID,
+		s.TTL,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		h.cfg.Session.CookieHTTPOnly,
+	)
+	c.Status(http.StatusOK)
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (h *mfaHandler) regenerateRecoveryCodes(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - regenerateRecoveryCodes - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), aid)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - regenerateRecoveryCodes: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFANotEnabled) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrMFANotEnabled)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodesResponse{codes})
+}
+
+type recoveryCodeVerifyRequest struct {
+	ChallengeID string `json:"challengeId" binding:"required"`
+	Code        string `json:"code" binding:"required,lte=32"`
+}
+
+func (h *mfaHandler) verifyRecoveryCode(c *gin.Context) {
+	var r recoveryCodeVerifyRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	s, err := h.mfaService.VerifyRecoveryCode(
+		c.Request.Context(),
+		r.ChallengeID,
+		r.Code,
+		service.Device{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.Header.Get("User-Agent"),
+		},
+	)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - verifyRecoveryCode: %w", err))
+
+		if errors.Is(err, apperrors.ErrRecoveryCodeNotFound) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrRecoveryCodeNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAChallengeNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAChallengeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.Session.CookieKey,
+		s.ID,
+		s.TTL,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		h.cfg.Session.CookieHTTPOnly,
+	)
+	c.Status(http.StatusOK)
+}
+
+func (h *mfaHandler) enableEmailOTP(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - enableEmailOTP - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	codes, err := h.mfaService.EnableEmailOTP(c.Request.Context(), aid)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - enableEmailOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFAAlreadyEnabled) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrMFAAlreadyEnabled)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodesResponse{codes})
+}
+
+func (h *mfaHandler) disableEmailOTP(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - disableEmailOTP - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.mfaService.DisableEmailOTP(c.Request.Context(), aid); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - disableEmailOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFANotEnabled) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrMFANotEnabled)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type mfaChallengeRequest struct {
+	ChallengeID string `json:"challengeId" binding:"required"`
+}
+
+func (h *mfaHandler) sendEmailOTP(c *gin.Context) {
+	var r mfaChallengeRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	err := h.mfaService.SendEmailOTP(
+		c.Request.Context(),
+		r.ChallengeID,
+		service.Device{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.Header.Get("User-Agent"),
+		},
+	)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - sendEmailOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFAEmailCodeCooldown) {
+			c.Header("Retry-After", strconv.Itoa(int(h.cfg.MFA.EmailCooldown.Seconds())))
+			abortWithError(c, http.StatusTooManyRequests, apperrors.ErrMFAEmailCodeCooldown)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAMethodNotAllowed) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrMFAMethodNotAllowed)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAChallengeNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAChallengeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type emailOTPVerifyRequest struct {
+	ChallengeID string `json:"challengeId" binding:"required"`
+	Code        string `json:"code" binding:"required,numeric,len=6"`
+}
+
+func (h *mfaHandler) verifyEmailOTP(c *gin.Context) {
+	var r emailOTPVerifyRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	s, err := h.mfaService.VerifyEmailOTP(
+		c.Request.Context(),
+		r.ChallengeID,
+		r.Code,
+		service.Device{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.Header.Get("User-Agent"),
+		},
+	)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - mfa - verifyEmailOTP: %w", err))
+
+		if errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAIncorrectCode)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAEmailCodeExpired) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAEmailCodeExpired)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAMethodNotAllowed) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrMFAMethodNotAllowed)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrMFAChallengeNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrMFAChallengeNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.Session.CookieKey,
+		s.ID,
+		s.TTL,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		h.cfg.Session.CookieHTTPOnly,
+	)
+	c.Status(http.StatusOK)
+}
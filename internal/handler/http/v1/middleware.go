@@ -2,7 +2,9 @@ package v1
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/ysomad/go-auth-service/pkg/apperrors"
 	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/ratelimit"
 	"github.com/ysomad/go-auth-service/pkg/utils"
 )
 
@@ -153,6 +156,63 @@ func setCSRFTokenMiddleware(l logger.Interface, cfg *config.Config) gin.HandlerF
 	}
 }
 
+// policyRateLimitMiddleware limits requests with token bucket policy configured by name,
+// requests are not limited if policy is not configured.
+func policyRateLimitMiddleware(l logger.Interface, cfg *config.Config, newLimiter ratelimit.NewFunc,
+	name string) gin.HandlerFunc {
+
+	p, ok := cfg.RateLimit.Policies[name]
+	if !ok || p.Limit <= 0 || p.Period <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return rateLimitMiddleware(l, newLimiter(name, p.Limit, p.Period), p.Key)
+}
+
+// rateLimitMiddleware takes token from bucket of the request keyed by "ip", "account" or "session"
+// and sets RateLimit-* headers, must be used after sessionMiddleware to key by account or session.
+func rateLimitMiddleware(l logger.Interface, lim ratelimit.Limiter, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := lim.Allow(c.Request.Context(), rateLimitKey(c, key))
+		if err != nil {
+			l.Error(fmt.Errorf("http - v1 - middleware - rateLimitMiddleware - lim.Allow: %w", err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.ResetAfter.Seconds()))))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			abortWithError(c, http.StatusTooManyRequests, apperrors.ErrTooManyRequests)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey returns key of the request bucket, falls back to client ip
+// if there is no account or session in context.
+func rateLimitKey(c *gin.Context, key string) string {
+	switch key {
+	case "account":
+		if aid := c.GetString("aid"); aid != "" {
+			return "account:" + aid
+		}
+	case "session":
+		if sid := c.GetString("sid"); sid != "" {
+			return "session:" + sid
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
 // accountID returns account id from context
 func accountID(c *gin.Context) (string, error) {
 	aid := c.GetString("aid")
@@ -7,10 +7,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/ysomad/go-auth-service/config"
 	"github.com/ysomad/go-auth-service/internal/service"
 
 	"github.com/ysomad/go-auth-service/pkg/apperrors"
 	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/ratelimit"
 	"github.com/ysomad/go-auth-service/pkg/validation"
 )
 
@@ -20,8 +22,8 @@ type sessionHandler struct {
 	sessionService service.Session
 }
 
-func newSessionHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin,
-	sess service.Session, auth service.Auth) {
+func newSessionHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin, cfg *config.Config,
+	newLimiter ratelimit.NewFunc, sess service.Session, auth service.Auth) {
 
 	h := &sessionHandler{l, v, sess}
 
@@ -35,7 +37,7 @@ func newSessionHandler(handler *gin.RouterGroup, l logger.Interface, v validatio
 				secure.DELETE("", h.terminateAll)
 			}
 
-			authenticated.GET("", h.get)
+			authenticated.GET("", policyRateLimitMiddleware(l, cfg, newLimiter, "session_list"), h.get)
 		}
 	}
 }
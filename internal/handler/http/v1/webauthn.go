@@ -0,0 +1,354 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/service"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/logger"
+	"github.com/ysomad/go-auth-service/pkg/validation"
+	"github.com/ysomad/go-auth-service/pkg/webauthn"
+)
+
+type webAuthnHandler struct {
+	log logger.Interface
+	validation.Gin
+	cfg             *config.Config
+	webAuthnService service.WebAuthn
+}
+
+func newWebAuthnHandler(handler *gin.RouterGroup, l logger.Interface, v validation.Gin, cfg *config.Config,
+	w service.WebAuthn, s service.Session, auth service.Auth) {
+
+	h := &webAuthnHandler{l, v, cfg, w}
+
+	g := handler.Group("/webauthn")
+	{
+		authenticated := g.Group("/", sessionMiddleware(l, s))
+		{
+			secure := authenticated.Group("/", tokenMiddleware(l, auth))
+			{
+				secure.POST("registration/begin", h.beginRegistration)
+				secure.DELETE("credentials/:credentialID", h.deleteCredential)
+			}
+
+			authenticated.POST("registration/finish", h.finishRegistration)
+			authenticated.GET("credentials", h.getCredentials)
+		}
+
+		g.POST("login/begin", h.beginLogin)
+		g.POST("login/finish", h.finishLogin)
+	}
+}
+
+// publicKeyCredentialDescriptor represents PublicKeyCredentialDescriptor of WebAuthn spec.
+type publicKeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type publicKeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type relyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// creationOptions represents PublicKeyCredentialCreationOptions of WebAuthn spec,
+// binary values are base64url encoded.
+type creationOptions struct {
+	Challenge              string                          `json:"challenge"`
+	RP                     relyingPartyEntity              `json:"rp"`
+	User                   userEntity                      `json:"user"`
+	PubKeyCredParams       []publicKeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                           `json:"timeout"`
+	ExcludeCredentials     []publicKeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection          `json:"authenticatorSelection"`
+	Attestation            string                          `json:"attestation"`
+}
+
+// requestOptions represents PublicKeyCredentialRequestOptions of WebAuthn spec,
+// allowed credentials are empty since discoverable credentials are used.
+type requestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	Timeout          int64  `json:"timeout"`
+	UserVerification string `json:"userVerification"`
+}
+
+type webAuthnCeremonyResponse struct {
+	CeremonyID string      `json:"ceremonyId"`
+	ExpiresAt  time.Time   `json:"expiresAt"`
+	PublicKey  interface{} `json:"publicKey"`
+}
+
+func (h *webAuthnHandler) beginRegistration(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - beginRegistration - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	r, err := h.webAuthnService.BeginRegistration(c.Request.Context(), aid, service.Device{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.Header.Get("User-Agent"),
+	})
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - beginRegistration: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	exclude := make([]publicKeyCredentialDescriptor, len(r.Credentials))
+	for i, cred := range r.Credentials {
+		exclude[i] = publicKeyCredentialDescriptor{"public-key", cred.ID}
+	}
+
+	c.JSON(http.StatusOK, webAuthnCeremonyResponse{
+		CeremonyID: r.Ceremony.ID,
+		ExpiresAt:  r.Ceremony.ExpiresAt,
+		PublicKey: creationOptions{
+			Challenge: r.Ceremony.Challenge,
+			RP:        relyingPartyEntity{h.cfg.WebAuthn.RPID, h.cfg.WebAuthn.RPName},
+			User: userEntity{
+				ID:          webauthn.Encoding.EncodeToString([]byte(r.Account.ID)),
+				Name:        r.Account.Email,
+				DisplayName: r.Account.Username,
+			},
+			PubKeyCredParams: []publicKeyCredentialParameters{
+				{"public-key", webauthn.AlgES256},
+				{"public-key", webauthn.AlgRS256},
+			},
+			Timeout:            h.cfg.WebAuthn.CeremonyTTL.Milliseconds(),
+			ExcludeCredentials: exclude,
+			AuthenticatorSelection: authenticatorSelection{
+				ResidentKey:      "required",
+				UserVerification: "required",
+			},
+			Attestation: "none",
+		},
+	})
+}
+
+type webAuthnRegistrationRequest struct {
+	CeremonyID        string `json:"ceremonyId" binding:"required"`
+	Name              string `json:"name" binding:"required,lte=64"`
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AttestationObject string `json:"attestationObject" binding:"required"`
+}
+
+func (h *webAuthnHandler) finishRegistration(c *gin.Context) {
+	var r webAuthnRegistrationRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - finishRegistration - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	att := service.WebAuthnAttestation{Name: r.Name}
+
+	att.ClientDataJSON, err = decodeBase64URL(r.ClientDataJSON)
+	if err == nil {
+		att.AttestationObject, err = decodeBase64URL(r.AttestationObject)
+	}
+
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - finishRegistration - decodeBase64URL: %w", err))
+		abortWithError(c, http.StatusBadRequest, apperrors.ErrWebAuthnVerificationFailed)
+		return
+	}
+
+	cred, err := h.webAuthnService.FinishRegistration(c.Request.Context(), aid, r.CeremonyID, att, service.Device{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.Header.Get("User-Agent"),
+	})
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - finishRegistration: %w", err))
+
+		if errors.Is(err, apperrors.ErrWebAuthnVerificationFailed) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrWebAuthnVerificationFailed)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrWebAuthnCeremonyNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) {
+			abortWithError(c, http.StatusBadRequest, apperrors.ErrWebAuthnCeremonyNotFound)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrWebAuthnCredentialAlreadyExist) {
+			abortWithError(c, http.StatusConflict, apperrors.ErrWebAuthnCredentialAlreadyExist)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cred)
+}
+
+func (h *webAuthnHandler) getCredentials(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - getCredentials - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	credentials, err := h.webAuthnService.GetAllCredentials(c.Request.Context(), aid)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - getCredentials: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+func (h *webAuthnHandler) deleteCredential(c *gin.Context) {
+	aid, err := accountID(c)
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - deleteCredential - accountID: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.webAuthnService.DeleteCredential(c.Request.Context(), aid, c.Param("credentialID")); err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - deleteCredential: %w", err))
+
+		if errors.Is(err, apperrors.ErrWebAuthnCredentialNotFound) {
+			abortWithError(c, http.StatusNotFound, apperrors.ErrWebAuthnCredentialNotFound)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *webAuthnHandler) beginLogin(c *gin.Context) {
+	ceremony, err := h.webAuthnService.BeginLogin(c.Request.Context(), service.Device{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.Header.Get("User-Agent"),
+	})
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - beginLogin: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, webAuthnCeremonyResponse{
+		CeremonyID: ceremony.ID,
+		ExpiresAt:  ceremony.ExpiresAt,
+		PublicKey: requestOptions{
+			Challenge:        ceremony.Challenge,
+			RPID:             h.cfg.WebAuthn.RPID,
+			Timeout:          h.cfg.WebAuthn.CeremonyTTL.Milliseconds(),
+			UserVerification: "required",
+		},
+	})
+}
+
+type webAuthnLoginRequest struct {
+	CeremonyID        string `json:"ceremonyId" binding:"required"`
+	CredentialID      string `json:"credentialId" binding:"required"`
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+}
+
+func (h *webAuthnHandler) finishLogin(c *gin.Context) {
+	var r webAuthnLoginRequest
+
+	if err := c.ShouldBindJSON(&r); err != nil {
+		abortWithValidationError(c, http.StatusBadRequest, h.TranslateError(err))
+		return
+	}
+
+	as := service.WebAuthnAssertion{CredentialID: strings.TrimRight(r.CredentialID, "=")}
+
+	var err error
+
+	as.ClientDataJSON, err = decodeBase64URL(r.ClientDataJSON)
+	if err == nil {
+		as.AuthenticatorData, err = decodeBase64URL(r.AuthenticatorData)
+	}
+	if err == nil {
+		as.Signature, err = decodeBase64URL(r.Signature)
+	}
+
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - finishLogin - decodeBase64URL: %w", err))
+		abortWithError(c, http.StatusBadRequest, apperrors.ErrWebAuthnVerificationFailed)
+		return
+	}
+
+	s, err := h.webAuthnService.FinishLogin(c.Request.Context(), r.CeremonyID, as, service.Device{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.Header.Get("User-Agent"),
+	})
+	if err != nil {
+		h.log.Error(fmt.Errorf("http - v1 - webauthn - finishLogin: %w", err))
+
+		if errors.Is(err, apperrors.ErrWebAuthnVerificationFailed) ||
+			errors.Is(err, apperrors.ErrWebAuthnCredentialNotFound) ||
+			errors.Is(err, apperrors.ErrWebAuthnCeremonyNotFound) ||
+			errors.Is(err, apperrors.ErrSessionDeviceMismatch) ||
+			errors.Is(err, apperrors.ErrAccountNotFound) {
+			abortWithError(c, http.StatusUnauthorized, apperrors.ErrWebAuthnVerificationFailed)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.SetCookie(
+		h.cfg.Session.CookieKey,
+		s.ID,
+		s.TTL,
+		apiPath,
+		h.cfg.Session.CookieDomain,
+		h.cfg.Session.CookieSecure,
+		h.cfg.Session.CookieHTTPOnly,
+	)
+	c.Status(http.StatusOK)
+}
+
+// decodeBase64URL decodes base64url value with or without padding.
+func decodeBase64URL(s string) ([]byte, error) {
+	return webauthn.Encoding.DecodeString(strings.TrimRight(s, "="))
+}
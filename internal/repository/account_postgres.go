@@ -30,8 +30,8 @@ func NewAccountRepo(pg *postgres.Postgres) *accountRepo {
 func (r *accountRepo) Create(ctx context.Context, a domain.Account) (string, error) {
 	sql, args, err := r.Builder.
 		Insert(_accTable).
-		Columns("username, email, password, is_verified").
-		Values(a.Username, a.Email, a.PasswordHash, a.Verified).
+		Columns("username, email, password, password_pepper_version, password_generated, is_verified").
+		Values(a.Username, a.Email, a.PasswordHash, a.PasswordPepperVersion, a.PasswordGenerated, a.Verified).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
@@ -58,7 +58,7 @@ func (r *accountRepo) Create(ctx context.Context, a domain.Account) (string, err
 
 func (r *accountRepo) FindByID(ctx context.Context, aid string) (domain.Account, error) {
 	sql, args, err := r.Builder.
-		Select("username, email, password, created_at, updated_at").
+		Select("username, email, password, password_pepper_version, password_generated, created_at, updated_at, is_verified, version").
 		From(_accTable).
 		Where(sq.Eq{"id": aid, "is_archive": false}).
 		ToSql()
@@ -72,8 +72,12 @@ func (r *accountRepo) FindByID(ctx context.Context, aid string) (domain.Account,
 		&acc.Username,
 		&acc.Email,
 		&acc.PasswordHash,
+		&acc.PasswordPepperVersion,
+		&acc.PasswordGenerated,
 		&acc.CreatedAt,
 		&acc.UpdatedAt,
+		&acc.Verified,
+		&acc.Version,
 	); err != nil {
 		if err == pgx.ErrNoRows {
 			return domain.Account{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrAccountNotFound)
@@ -87,7 +91,7 @@ func (r *accountRepo) FindByID(ctx context.Context, aid string) (domain.Account,
 
 func (r *accountRepo) FindByEmail(ctx context.Context, email string) (domain.Account, error) {
 	sql, args, err := r.Builder.
-		Select("id, username, password, created_at, updated_at").
+		Select("id, username, password, password_pepper_version, password_generated, created_at, updated_at, is_verified").
 		From(_accTable).
 		Where(sq.Eq{"email": email, "is_archive": false}).
 		ToSql()
@@ -101,8 +105,11 @@ func (r *accountRepo) FindByEmail(ctx context.Context, email string) (domain.Acc
 		&acc.ID,
 		&acc.Username,
 		&acc.PasswordHash,
+		&acc.PasswordPepperVersion,
+		&acc.PasswordGenerated,
 		&acc.CreatedAt,
 		&acc.UpdatedAt,
+		&acc.Verified,
 	); err != nil {
 		if err == pgx.ErrNoRows {
 			return domain.Account{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrAccountNotFound)
@@ -114,11 +121,137 @@ func (r *accountRepo) FindByEmail(ctx context.Context, email string) (domain.Acc
 	return acc, nil
 }
 
+func (r *accountRepo) FindByUsername(ctx context.Context, username string) (domain.Account, error) {
+	sql, args, err := r.Builder.
+		Select("id, email, password, password_pepper_version, password_generated, created_at, updated_at, is_verified").
+		From(_accTable).
+		Where(sq.Eq{"username": username, "is_archive": false}).
+		ToSql()
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	acc := domain.Account{Username: username}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&acc.ID,
+		&acc.Email,
+		&acc.PasswordHash,
+		&acc.PasswordPepperVersion,
+		&acc.PasswordGenerated,
+		&acc.CreatedAt,
+		&acc.UpdatedAt,
+		&acc.Verified,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Account{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrAccountNotFound)
+		}
+
+		return domain.Account{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (r *accountRepo) Exists(ctx context.Context, column, value string) (bool, error) {
+	sql, args, err := r.Builder.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From(_accTable).
+		Where(sq.Eq{column: value}).
+		Suffix(")").
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	var exists bool
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *accountRepo) FindArchivedByID(ctx context.Context, aid string) (domain.Account, error) {
+	acc, err := r.findArchived(ctx, sq.Eq{"id": aid})
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("r.findArchived: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (r *accountRepo) FindArchivedByEmail(ctx context.Context, email string) (domain.Account, error) {
+	acc, err := r.findArchived(ctx, sq.Eq{"email": email})
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("r.findArchived: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (r *accountRepo) FindArchivedByUsername(ctx context.Context, username string) (domain.Account, error) {
+	acc, err := r.findArchived(ctx, sq.Eq{"username": username})
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("r.findArchived: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (r *accountRepo) FindArchivedBefore(ctx context.Context, t time.Time, limit int) ([]string, error) {
+	sql, args, err := r.Builder.
+		Select("id").
+		From(_accTable).
+		Where(sq.Eq{"is_archive": true}).
+		Where(sq.Lt{"archived_at": t}).
+		OrderBy("archived_at").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var aid string
+
+		if err = rows.Scan(&aid); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		ids = append(ids, aid)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (r *accountRepo) Archive(ctx context.Context, aid string, archive bool) error {
+	now := time.Now()
+
+	var archivedAt *time.Time
+	if archive {
+		archivedAt = &now
+	}
+
 	sql, args, err := r.Builder.
 		Update(_accTable).
 		Set("is_archive", archive).
-		Set("updated_at", time.Now()).
+		Set("archived_at", archivedAt).
+		Set("updated_at", now).
 		Where(sq.Eq{"id": aid, "is_archive": !archive}).
 		ToSql()
 	if err != nil {
@@ -136,3 +269,231 @@ func (r *accountRepo) Archive(ctx context.Context, aid string, archive bool) err
 
 	return nil
 }
+
+func (r *accountRepo) Verify(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Update(_accTable).
+		Set("is_verified", true).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": aid, "is_archive": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrAccountNotFound)
+	}
+
+	return nil
+}
+
+func (r *accountRepo) Update(ctx context.Context, a domain.Account) error {
+	sql, args, err := r.Builder.
+		Update(_accTable).
+		Set("username", a.Username).
+		Set("updated_at", time.Now()).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": a.ID, "version": a.Version, "is_archive": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		if errors.As(err, &pgErr) {
+
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrAccountAlreadyExist)
+			}
+		}
+
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrAccountVersionConflict)
+	}
+
+	return nil
+}
+
+func (r *accountRepo) UpdatePassword(ctx context.Context, aid, passwordHash string, pepperVersion int) error {
+	sql, args, err := r.Builder.
+		Update(_accTable).
+		Set("password", passwordHash).
+		Set("password_pepper_version", pepperVersion).
+		Set("password_generated", false).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": aid, "is_archive": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrAccountNotFound)
+	}
+
+	return nil
+}
+
+func (r *accountRepo) RehashPassword(ctx context.Context, aid, oldHash, newHash string, pepperVersion int) error {
+	sql, args, err := r.Builder.
+		Update(_accTable).
+		Set("password", newHash).
+		Set("password_pepper_version", pepperVersion).
+		Where(sq.Eq{"id": aid, "password": oldHash}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountRepo) UpdateEmail(ctx context.Context, aid, email string) error {
+	sql, args, err := r.Builder.
+		Update(_accTable).
+		Set("email", email).
+		Set("is_verified", true).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": aid, "is_archive": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		if errors.As(err, &pgErr) {
+
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrAccountAlreadyExist)
+			}
+		}
+
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrAccountNotFound)
+	}
+
+	return nil
+}
+
+func (r *accountRepo) findArchived(ctx context.Context, where sq.Eq) (domain.Account, error) {
+	where["is_archive"] = true
+
+	sql, args, err := r.Builder.
+		Select("id, username, email, password, password_pepper_version, password_generated, created_at, updated_at, archived_at, is_verified").
+		From(_accTable).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	acc := domain.Account{Archive: true}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&acc.ID,
+		&acc.Username,
+		&acc.Email,
+		&acc.PasswordHash,
+		&acc.PasswordPepperVersion,
+		&acc.PasswordGenerated,
+		&acc.CreatedAt,
+		&acc.UpdatedAt,
+		&acc.ArchivedAt,
+		&acc.Verified,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Account{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrAccountNotFound)
+		}
+
+		return domain.Account{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (r *accountRepo) Erase(ctx context.Context, aid string, ar domain.AuditRecord) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql, args, err := r.Builder.
+		Delete(_auditTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	sql, args, err = r.Builder.
+		Delete(_accTable).
+		Where(sq.Eq{"id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	ct, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("tx.Exec: %w", apperrors.ErrAccountNotFound)
+	}
+
+	if ar.Metadata == nil {
+		ar.Metadata = map[string]string{}
+	}
+
+	sql, args, err = r.Builder.
+		Insert(_auditTable).
+		Columns("account_id, action, metadata, created_at").
+		Values(ar.AccountID, ar.Action, ar.Metadata, ar.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
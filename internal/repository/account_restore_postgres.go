@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _accountRestoreTable = "account_restores"
+
+type accountRestoreRepo struct {
+	*postgres.Postgres
+}
+
+func NewAccountRestoreRepo(pg *postgres.Postgres) *accountRestoreRepo {
+	return &accountRestoreRepo{pg}
+}
+
+func (r *accountRestoreRepo) Create(ctx context.Context, ar domain.AccountRestore) error {
+	sql, args, err := r.Builder.
+		Insert(_accountRestoreTable).
+		Columns("token, account_id, expires_at, created_at").
+		Values(ar.TokenHash, ar.AccountID, ar.ExpiresAt, ar.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountRestoreRepo) Consume(ctx context.Context, tokenHash string) (domain.AccountRestore, error) {
+	sql, args, err := r.Builder.
+		Delete(_accountRestoreTable).
+		Where(sq.Eq{"token": tokenHash}).
+		Suffix("RETURNING account_id, expires_at, created_at").
+		ToSql()
+	if err != nil {
+		return domain.AccountRestore{}, fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	ar := domain.AccountRestore{TokenHash: tokenHash}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&ar.AccountID, &ar.ExpiresAt, &ar.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.AccountRestore{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrAccountRestoreTokenNotFound)
+		}
+
+		return domain.AccountRestore{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return ar, nil
+}
+
+func (r *accountRestoreRepo) DeleteAll(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_accountRestoreTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _auditTable = "audit_records"
+
+type auditRepo struct {
+	*postgres.Postgres
+}
+
+func NewAuditRepo(pg *postgres.Postgres) *auditRepo {
+	return &auditRepo{pg}
+}
+
+func (r *auditRepo) Create(ctx context.Context, ar domain.AuditRecord) error {
+	if ar.Metadata == nil {
+		ar.Metadata = map[string]string{}
+	}
+
+	sql, args, err := r.Builder.
+		Insert(_auditTable).
+		Columns("account_id, action, metadata, created_at").
+		Values(ar.AccountID, ar.Action, ar.Metadata, ar.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *auditRepo) FindAll(ctx context.Context, aid string) ([]domain.AuditRecord, error) {
+	sql, args, err := r.Builder.
+		Select("id, action, metadata, created_at").
+		From(_auditTable).
+		Where(sq.Eq{"account_id": aid}).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var records []domain.AuditRecord
+
+	for rows.Next() {
+		ar := domain.AuditRecord{AccountID: aid}
+
+		if err = rows.Scan(&ar.ID, &ar.Action, &ar.Metadata, &ar.CreatedAt); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		records = append(records, ar)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _emailChangeTable = "email_changes"
+
+type emailChangeRepo struct {
+	*postgres.Postgres
+}
+
+func NewEmailChangeRepo(pg *postgres.Postgres) *emailChangeRepo {
+	return &emailChangeRepo{pg}
+}
+
+func (r *emailChangeRepo) Save(ctx context.Context, ec domain.EmailChange) error {
+	sql, args, err := r.Builder.
+		Insert(_emailChangeTable).
+		Columns("account_id, old_email, new_email, token, revert_token, expires_at, revert_expires_at, created_at").
+		Values(
+			ec.AccountID,
+			ec.OldEmail,
+			ec.NewEmail,
+			ec.TokenHash,
+			ec.RevertTokenHash,
+			ec.ExpiresAt,
+			ec.RevertExpiresAt,
+			ec.CreatedAt,
+		).
+		Suffix(`ON CONFLICT (account_id) DO UPDATE SET
+			old_email = EXCLUDED.old_email,
+			new_email = EXCLUDED.new_email,
+			token = EXCLUDED.token,
+			revert_token = EXCLUDED.revert_token,
+			is_confirmed = false,
+			expires_at = EXCLUDED.expires_at,
+			revert_expires_at = EXCLUDED.revert_expires_at,
+			created_at = EXCLUDED.created_at`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *emailChangeRepo) FindByToken(ctx context.Context, tokenHash string) (domain.EmailChange, error) {
+	ec, err := r.find(ctx, sq.Eq{"token": tokenHash})
+	if err != nil {
+		return domain.EmailChange{}, fmt.Errorf("r.find: %w", err)
+	}
+
+	return ec, nil
+}
+
+func (r *emailChangeRepo) FindByRevertToken(ctx context.Context, tokenHash string) (domain.EmailChange, error) {
+	ec, err := r.find(ctx, sq.Eq{"revert_token": tokenHash})
+	if err != nil {
+		return domain.EmailChange{}, fmt.Errorf("r.find: %w", err)
+	}
+
+	return ec, nil
+}
+
+func (r *emailChangeRepo) Confirm(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Update(_emailChangeTable).
+		Set("is_confirmed", true).
+		Where(sq.Eq{"account_id": aid, "is_confirmed": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrEmailChangeNotFound)
+	}
+
+	return nil
+}
+
+func (r *emailChangeRepo) Delete(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_emailChangeTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *emailChangeRepo) find(ctx context.Context, where sq.Eq) (domain.EmailChange, error) {
+	sql, args, err := r.Builder.
+		Select("account_id, old_email, new_email, token, revert_token, is_confirmed, expires_at, revert_expires_at, created_at").
+		From(_emailChangeTable).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return domain.EmailChange{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	var ec domain.EmailChange
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&ec.AccountID,
+		&ec.OldEmail,
+		&ec.NewEmail,
+		&ec.TokenHash,
+		&ec.RevertTokenHash,
+		&ec.Confirmed,
+		&ec.ExpiresAt,
+		&ec.RevertExpiresAt,
+		&ec.CreatedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.EmailChange{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrEmailChangeNotFound)
+		}
+
+		return domain.EmailChange{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return ec, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _emailOTPTable = "account_email_otp"
+
+type emailOTPRepo struct {
+	*postgres.Postgres
+}
+
+func NewEmailOTPRepo(pg *postgres.Postgres) *emailOTPRepo {
+	return &emailOTPRepo{pg}
+}
+
+func (r *emailOTPRepo) Enable(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Insert(_emailOTPTable).
+		Columns("account_id").
+		Values(aid).
+		Suffix("ON CONFLICT (account_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrMFAAlreadyEnabled)
+	}
+
+	return nil
+}
+
+func (r *emailOTPRepo) Enabled(ctx context.Context, aid string) (bool, error) {
+	sql, args, err := r.Builder.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From(_emailOTPTable).
+		Where(sq.Eq{"account_id": aid}).
+		Suffix(")").
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	var enabled bool
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&enabled); err != nil {
+		return false, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return enabled, nil
+}
+
+func (r *emailOTPRepo) Disable(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_emailOTPTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrMFANotEnabled)
+	}
+
+	return nil
+}
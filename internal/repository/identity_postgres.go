@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _identityTable = "identities"
+
+type identityRepo struct {
+	*postgres.Postgres
+}
+
+func NewIdentityRepo(pg *postgres.Postgres) *identityRepo {
+	return &identityRepo{pg}
+}
+
+func (r *identityRepo) Create(ctx context.Context, i domain.Identity) error {
+	sql, args, err := r.Builder.
+		Insert(_identityTable).
+		Columns("provider, provider_user_id, account_id, email, created_at").
+		Values(i.Provider, i.ProviderUserID, i.AccountID, i.Email, i.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+
+		if errors.As(err, &pgErr) {
+
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrIdentityAlreadyExist)
+			}
+		}
+
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *identityRepo) FindByProviderUserID(ctx context.Context, provider, providerUserID string) (domain.Identity, error) {
+	sql, args, err := r.Builder.
+		Select("account_id, email, created_at").
+		From(_identityTable).
+		Where(sq.Eq{"provider": provider, "provider_user_id": providerUserID}).
+		ToSql()
+	if err != nil {
+		return domain.Identity{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	i := domain.Identity{Provider: provider, ProviderUserID: providerUserID}
+
+	var email *string
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&i.AccountID, &email, &i.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Identity{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrIdentityNotFound)
+		}
+
+		return domain.Identity{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	if email != nil {
+		i.Email = *email
+	}
+
+	return i, nil
+}
+
+func (r *identityRepo) FindAll(ctx context.Context, aid string) ([]domain.Identity, error) {
+	sql, args, err := r.Builder.
+		Select("provider, provider_user_id, email, created_at").
+		From(_identityTable).
+		Where(sq.Eq{"account_id": aid}).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []domain.Identity
+
+	for rows.Next() {
+		i := domain.Identity{AccountID: aid}
+
+		var email *string
+
+		if err = rows.Scan(&i.Provider, &i.ProviderUserID, &email, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		if email != nil {
+			i.Email = *email
+		}
+
+		identities = append(identities, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (r *identityRepo) Delete(ctx context.Context, aid, provider string) error {
+	sql, args, err := r.Builder.
+		Delete(_identityTable).
+		Where(sq.Eq{"account_id": aid, "provider": provider}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrIdentityNotFound)
+	}
+
+	return nil
+}
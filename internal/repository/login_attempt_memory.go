@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+)
+
+type loginAttemptEntry struct {
+	attempts  domain.LoginAttempts
+	expiresAt time.Time
+}
+
+// loginAttemptMemoryRepo keeps login attempts in process memory,
+// it is used when Redis is not available and is not shared between instances.
+type loginAttemptMemoryRepo struct {
+	mu      sync.Mutex
+	entries map[string]*loginAttemptEntry
+	cleaned time.Time
+}
+
+func NewLoginAttemptMemoryRepo() *loginAttemptMemoryRepo {
+	return &loginAttemptMemoryRepo{
+		entries: make(map[string]*loginAttemptEntry),
+		cleaned: time.Now(),
+	}
+}
+
+func (r *loginAttemptMemoryRepo) Increment(ctx context.Context, key string, ttl time.Duration) (domain.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.cleanup(now)
+
+	e, ok := r.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		e = &loginAttemptEntry{}
+		r.entries[key] = e
+	}
+
+	e.attempts.Failures++
+	e.expiresAt = now.Add(ttl)
+
+	return e.attempts, nil
+}
+
+func (r *loginAttemptMemoryRepo) Decrement(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[key]; ok && e.attempts.Failures > 0 {
+		e.attempts.Failures--
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMemoryRepo) Lock(ctx context.Context, key string, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[key]
+	if !ok || e.attempts.Locked() {
+		return false, nil
+	}
+
+	e.attempts.LockedUntil = until
+
+	return true, nil
+}
+
+func (r *loginAttemptMemoryRepo) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.entries, key)
+
+	return nil
+}
+
+// cleanup removes expired entries once in a while to not keep every key in memory forever.
+func (r *loginAttemptMemoryRepo) cleanup(now time.Time) {
+	if now.Sub(r.cleaned) < time.Minute {
+		return
+	}
+
+	for k, e := range r.entries {
+		if now.After(e.expiresAt) {
+			delete(r.entries, k)
+		}
+	}
+
+	r.cleaned = now
+}
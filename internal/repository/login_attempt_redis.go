@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+)
+
+const _loginAttemptPrefix = "loginAttempts:"
+
+type loginAttemptRedisRepo struct {
+	*redis.Client
+}
+
+func NewLoginAttemptRedisRepo(rdb *redis.Client) *loginAttemptRedisRepo {
+	return &loginAttemptRedisRepo{rdb}
+}
+
+func (r *loginAttemptRedisRepo) Increment(ctx context.Context, key string, ttl time.Duration) (domain.LoginAttempts, error) {
+	var all *redis.StringStringMapCmd
+
+	_, err := r.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, _loginAttemptPrefix+key, "failures", 1)
+		pipe.Expire(ctx, _loginAttemptPrefix+key, ttl)
+		all = pipe.HGetAll(ctx, _loginAttemptPrefix+key)
+		return nil
+	})
+	if err != nil {
+		return domain.LoginAttempts{}, fmt.Errorf("r.TxPipelined: %w", err)
+	}
+
+	a, err := parseLoginAttempts(all.Val())
+	if err != nil {
+		return domain.LoginAttempts{}, fmt.Errorf("parseLoginAttempts: %w", err)
+	}
+
+	return a, nil
+}
+
+// decrementScript decrements failures only if key exists to not create key without ttl.
+var decrementScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HINCRBY", KEYS[1], "failures", -1)
+end
+return 0
+`)
+
+func (r *loginAttemptRedisRepo) Decrement(ctx context.Context, key string) error {
+	if err := decrementScript.Run(ctx, r, []string{_loginAttemptPrefix + key}).Err(); err != nil {
+		return fmt.Errorf("decrementScript.Run: %w", err)
+	}
+
+	return nil
+}
+
+// lockScript sets lockedUntil if current lock is released, ARGV[1] is current time and ARGV[2] is lock time in ms.
+var lockScript = redis.NewScript(`
+local lockedUntil = tonumber(redis.call("HGET", KEYS[1], "lockedUntil") or "0")
+if lockedUntil > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("HSET", KEYS[1], "lockedUntil", ARGV[2])
+return 1
+`)
+
+func (r *loginAttemptRedisRepo) Lock(ctx context.Context, key string, until time.Time) (bool, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	ms := until.UnixNano() / int64(time.Millisecond)
+
+	locked, err := lockScript.Run(ctx, r, []string{_loginAttemptPrefix + key}, now, ms).Int()
+	if err != nil {
+		return false, fmt.Errorf("lockScript.Run: %w", err)
+	}
+
+	return locked == 1, nil
+}
+
+func (r *loginAttemptRedisRepo) Delete(ctx context.Context, key string) error {
+	if err := r.Del(ctx, _loginAttemptPrefix+key).Err(); err != nil {
+		return fmt.Errorf("r.Del: %w", err)
+	}
+
+	return nil
+}
+
+func parseLoginAttempts(res map[string]string) (domain.LoginAttempts, error) {
+	var (
+		a   domain.LoginAttempts
+		err error
+	)
+
+	if v, ok := res["failures"]; ok {
+		if a.Failures, err = strconv.Atoi(v); err != nil {
+			return domain.LoginAttempts{}, fmt.Errorf("strconv.Atoi: %w", err)
+		}
+	}
+
+	if v, ok := res["lockedUntil"]; ok {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return domain.LoginAttempts{}, fmt.Errorf("strconv.ParseInt: %w", err)
+		}
+
+		a.LockedUntil = time.Unix(0, ms*int64(time.Millisecond))
+	}
+
+	return a, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _magicLinkTable = "magic_links"
+
+type magicLinkRepo struct {
+	*postgres.Postgres
+}
+
+func NewMagicLinkRepo(pg *postgres.Postgres) *magicLinkRepo {
+	return &magicLinkRepo{pg}
+}
+
+func (r *magicLinkRepo) Create(ctx context.Context, l domain.MagicLink) error {
+	sql, args, err := r.Builder.
+		Insert(_magicLinkTable).
+		Columns("token, account_id, browser_token, user_agent, ip, expires_at, created_at").
+		Values(l.TokenHash, l.AccountID, l.BrowserTokenHash, l.UserAgent, l.IP, l.ExpiresAt, l.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// Consume deletes magic link requested from the browser with given token hash and returns it,
+// link opened in another browser is kept so it can still be used by its owner.
+func (r *magicLinkRepo) Consume(ctx context.Context, tokenHash, browserTokenHash string) (domain.MagicLink, error) {
+	sql, args, err := r.Builder.
+		Delete(_magicLinkTable).
+		Where(sq.Eq{"token": tokenHash, "browser_token": browserTokenHash}).
+		Suffix("RETURNING account_id, user_agent, ip, expires_at, created_at").
+		ToSql()
+	if err != nil {
+		return domain.MagicLink{}, fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	l := domain.MagicLink{TokenHash: tokenHash, BrowserTokenHash: browserTokenHash}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&l.AccountID,
+		&l.UserAgent,
+		&l.IP,
+		&l.ExpiresAt,
+		&l.CreatedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.MagicLink{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrMagicLinkNotFound)
+		}
+
+		return domain.MagicLink{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return l, nil
+}
+
+func (r *magicLinkRepo) DeleteAll(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_magicLinkTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+)
+
+type mfaChallengeRepo struct {
+	*mongo.Collection
+}
+
+func NewMFAChallengeRepo(db *mongo.Database) *mfaChallengeRepo {
+	return &mfaChallengeRepo{db.Collection("mfaChallenges")}
+}
+
+func (r *mfaChallengeRepo) Create(ctx context.Context, c domain.MFAChallenge) error {
+	ttlIndex := mongo.IndexModel{
+		Keys:    bsonx.Doc{{Key: "expiresAt", Value: bsonx.Int32(1)}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := r.Indexes().CreateOne(ctx, ttlIndex)
+	if err != nil {
+		return fmt.Errorf("r.Indexes.CreateOne: %w", err)
+	}
+
+	_, err = r.InsertOne(ctx, c)
+	if err != nil {
+		return fmt.Errorf("r.InsertOne: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaChallengeRepo) FindByID(ctx context.Context, cid string) (domain.MFAChallenge, error) {
+	var c domain.MFAChallenge
+
+	if err := r.FindOne(ctx, bson.M{"_id": cid}).Decode(&c); err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return domain.MFAChallenge{}, fmt.Errorf("r.FindOne.Decode: %w", apperrors.ErrMFAChallengeNotFound)
+		}
+
+		return domain.MFAChallenge{}, fmt.Errorf("r.FindOne.Decode: %w", err)
+	}
+
+	return c, nil
+}
+
+// ClaimAttempt increments attempts of challenge in single update, so parallel attempts cannot exceed max.
+func (r *mfaChallengeRepo) ClaimAttempt(ctx context.Context, cid string, max int) error {
+	err := r.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": cid, "attempts": bson.M{"$lt": max}},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+	).Err()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("r.FindOneAndUpdate: %w", apperrors.ErrMFAChallengeNotFound)
+		}
+
+		return fmt.Errorf("r.FindOneAndUpdate: %w", err)
+	}
+
+	return nil
+}
+
+// SetEmailCode sets email code of challenge if previous code was sent before given time.
+func (r *mfaChallengeRepo) SetEmailCode(ctx context.Context, cid, codeHash string, expiresAt, sentBefore time.Time) error {
+	res, err := r.UpdateOne(
+		ctx,
+		bson.M{"_id": cid, "emailCodeSentAt": bson.M{"$lte": sentBefore}},
+		bson.M{"$set": bson.M{
+			"emailCodeHash":      codeHash,
+			"emailCodeSentAt":    time.Now(),
+			"emailCodeExpiresAt": expiresAt,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("r.UpdateOne: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("r.UpdateOne: %w", apperrors.ErrMFAEmailCodeCooldown)
+	}
+
+	return nil
+}
+
+func (r *mfaChallengeRepo) Delete(ctx context.Context, cid string) error {
+	res, err := r.DeleteOne(ctx, bson.M{"_id": cid})
+	if err != nil {
+		return fmt.Errorf("r.DeleteOne: %w", err)
+	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("r.DeleteOne: %w", apperrors.ErrMFAChallengeNotFound)
+	}
+
+	return nil
+}
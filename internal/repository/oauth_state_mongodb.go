@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+)
+
+type oauthStateRepo struct {
+	*mongo.Collection
+}
+
+func NewOAuthStateRepo(db *mongo.Database) *oauthStateRepo {
+	return &oauthStateRepo{db.Collection("oauthStates")}
+}
+
+func (r *oauthStateRepo) Create(ctx context.Context, s domain.OAuthState) error {
+	ttlIndex := mongo.IndexModel{
+		Keys:    bsonx.Doc{{Key: "expiresAt", Value: bsonx.Int32(1)}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := r.Indexes().CreateOne(ctx, ttlIndex)
+	if err != nil {
+		return fmt.Errorf("r.Indexes.CreateOne: %w", err)
+	}
+
+	_, err = r.InsertOne(ctx, s)
+	if err != nil {
+		return fmt.Errorf("r.InsertOne: %w", err)
+	}
+
+	return nil
+}
+
+// Consume deletes state issued for account and provider to browser and returns it so state can be used only once,
+// state received in another browser is kept.
+func (r *oauthStateRepo) Consume(ctx context.Context, stateHash, aid, provider,
+	browserTokenHash string) (domain.OAuthState, error) {
+
+	var s domain.OAuthState
+
+	filter := bson.M{
+		"_id":              stateHash,
+		"accountId":        aid,
+		"provider":         provider,
+		"browserTokenHash": browserTokenHash,
+	}
+
+	if err := r.FindOneAndDelete(ctx, filter).Decode(&s); err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return domain.OAuthState{}, fmt.Errorf("r.FindOneAndDelete.Decode: %w", apperrors.ErrOAuthStateNotFound)
+		}
+
+		return domain.OAuthState{}, fmt.Errorf("r.FindOneAndDelete.Decode: %w", err)
+	}
+
+	return s, nil
+}
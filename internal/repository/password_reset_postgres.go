@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _passwordResetTable = "password_resets"
+
+type passwordResetRepo struct {
+	*postgres.Postgres
+}
+
+func NewPasswordResetRepo(pg *postgres.Postgres) *passwordResetRepo {
+	return &passwordResetRepo{pg}
+}
+
+func (r *passwordResetRepo) Create(ctx context.Context, pr domain.PasswordReset) error {
+	sql, args, err := r.Builder.
+		Insert(_passwordResetTable).
+		Columns("token, account_id, expires_at, created_at").
+		Values(pr.TokenHash, pr.AccountID, pr.ExpiresAt, pr.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepo) Find(ctx context.Context, tokenHash string) (domain.PasswordReset, error) {
+	sql, args, err := r.Builder.
+		Select("account_id, expires_at, created_at").
+		From(_passwordResetTable).
+		Where(sq.Eq{"token": tokenHash}).
+		ToSql()
+	if err != nil {
+		return domain.PasswordReset{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	pr := domain.PasswordReset{TokenHash: tokenHash}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&pr.AccountID, &pr.ExpiresAt, &pr.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.PasswordReset{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrPasswordResetTokenNotFound)
+		}
+
+		return domain.PasswordReset{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return pr, nil
+}
+
+func (r *passwordResetRepo) Consume(ctx context.Context, tokenHash string) (domain.PasswordReset, error) {
+	sql, args, err := r.Builder.
+		Delete(_passwordResetTable).
+		Where(sq.Eq{"token": tokenHash}).
+		Suffix("RETURNING account_id, expires_at, created_at").
+		ToSql()
+	if err != nil {
+		return domain.PasswordReset{}, fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	pr := domain.PasswordReset{TokenHash: tokenHash}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&pr.AccountID, &pr.ExpiresAt, &pr.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.PasswordReset{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrPasswordResetTokenNotFound)
+		}
+
+		return domain.PasswordReset{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return pr, nil
+}
+
+func (r *passwordResetRepo) DeleteAll(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_passwordResetTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _recoveryCodeTable = "recovery_codes"
+
+type recoveryCodeRepo struct {
+	*postgres.Postgres
+}
+
+func NewRecoveryCodeRepo(pg *postgres.Postgres) *recoveryCodeRepo {
+	return &recoveryCodeRepo{pg}
+}
+
+func (r *recoveryCodeRepo) Replace(ctx context.Context, aid string, codes []domain.RecoveryCode) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql, args, err := r.Builder.
+		Delete(_recoveryCodeTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	b := r.Builder.
+		Insert(_recoveryCodeTable).
+		Columns("account_id, code")
+
+	for _, c := range codes {
+		b = b.Values(aid, c.CodeHash)
+	}
+
+	sql, args, err = b.ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *recoveryCodeRepo) Use(ctx context.Context, aid, codeHash string) error {
+	sql, args, err := r.Builder.
+		Update(_recoveryCodeTable).
+		Set("used_at", time.Now()).
+		Where(sq.Eq{"account_id": aid, "code": codeHash, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrRecoveryCodeNotFound)
+	}
+
+	return nil
+}
+
+func (r *recoveryCodeRepo) CountUnused(ctx context.Context, aid string) (int, error) {
+	sql, args, err := r.Builder.
+		Select("count(*)").
+		From(_recoveryCodeTable).
+		Where(sq.Eq{"account_id": aid, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	var n int
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return n, nil
+}
+
+func (r *recoveryCodeRepo) DeleteAll(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_recoveryCodeTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _totpTable = "account_totp"
+
+type totpRepo struct {
+	*postgres.Postgres
+}
+
+func NewTOTPRepo(pg *postgres.Postgres) *totpRepo {
+	return &totpRepo{pg}
+}
+
+func (r *totpRepo) Save(ctx context.Context, t domain.TOTP) error {
+	sql, args, err := r.Builder.
+		Insert(_totpTable).
+		Columns("account_id, secret, created_at").
+		Values(t.AccountID, t.EncryptedSecret, t.CreatedAt).
+		Suffix(`ON CONFLICT (account_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			is_confirmed = false,
+			last_used_step = 0,
+			created_at = EXCLUDED.created_at
+			WHERE account_totp.is_confirmed = false`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrMFAAlreadyEnabled)
+	}
+
+	return nil
+}
+
+func (r *totpRepo) FindByAccountID(ctx context.Context, aid string) (domain.TOTP, error) {
+	sql, args, err := r.Builder.
+		Select("secret, is_confirmed, last_used_step, created_at").
+		From(_totpTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return domain.TOTP{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	t := domain.TOTP{AccountID: aid}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&t.EncryptedSecret,
+		&t.Confirmed,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.TOTP{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrMFATOTPNotFound)
+		}
+
+		return domain.TOTP{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return t, nil
+}
+
+func (r *totpRepo) Confirm(ctx context.Context, aid string, step int64) error {
+	sql, args, err := r.Builder.
+		Update(_totpTable).
+		Set("is_confirmed", true).
+		Set("last_used_step", step).
+		Where(sq.Eq{"account_id": aid, "is_confirmed": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrMFAAlreadyEnabled)
+	}
+
+	return nil
+}
+
+// UseStep sets last used time step of confirmed totp if it is greater than current one
+// to prevent replay of one-time passwords.
+func (r *totpRepo) UseStep(ctx context.Context, aid string, step int64) error {
+	sql, args, err := r.Builder.
+		Update(_totpTable).
+		Set("last_used_step", step).
+		Where(sq.Eq{"account_id": aid, "is_confirmed": true}).
+		Where(sq.Lt{"last_used_step": step}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrMFAIncorrectCode)
+	}
+
+	return nil
+}
+
+func (r *totpRepo) Delete(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_totpTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrMFATOTPNotFound)
+	}
+
+	return nil
+}
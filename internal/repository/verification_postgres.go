@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _verificationTable = "account_verifications"
+
+type verificationRepo struct {
+	*postgres.Postgres
+}
+
+func NewVerificationRepo(pg *postgres.Postgres) *verificationRepo {
+	return &verificationRepo{pg}
+}
+
+func (r *verificationRepo) Save(ctx context.Context, v domain.Verification) error {
+	sql, args, err := r.Builder.
+		Insert(_verificationTable).
+		Columns("account_id, code, expires_at, created_at").
+		Values(v.AccountID, v.CodeHash, v.ExpiresAt, v.CreatedAt).
+		Suffix("ON CONFLICT (account_id) DO UPDATE SET code = EXCLUDED.code, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *verificationRepo) FindByAccountID(ctx context.Context, aid string) (domain.Verification, error) {
+	sql, args, err := r.Builder.
+		Select("code, expires_at, created_at").
+		From(_verificationTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return domain.Verification{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	v := domain.Verification{AccountID: aid}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&v.CodeHash, &v.ExpiresAt, &v.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Verification{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrVerificationCodeNotFound)
+		}
+
+		return domain.Verification{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return v, nil
+}
+
+func (r *verificationRepo) FindByCode(ctx context.Context, codeHash string) (domain.Verification, error) {
+	sql, args, err := r.Builder.
+		Select("account_id, expires_at, created_at").
+		From(_verificationTable).
+		Where(sq.Eq{"code": codeHash}).
+		ToSql()
+	if err != nil {
+		return domain.Verification{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	v := domain.Verification{CodeHash: codeHash}
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(&v.AccountID, &v.ExpiresAt, &v.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Verification{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrVerificationCodeNotFound)
+		}
+
+		return domain.Verification{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	return v, nil
+}
+
+func (r *verificationRepo) Delete(ctx context.Context, aid string) error {
+	sql, args, err := r.Builder.
+		Delete(_verificationTable).
+		Where(sq.Eq{"account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+)
+
+type webAuthnCeremonyRepo struct {
+	*mongo.Collection
+}
+
+func NewWebAuthnCeremonyRepo(db *mongo.Database) *webAuthnCeremonyRepo {
+	return &webAuthnCeremonyRepo{db.Collection("webAuthnCeremonies")}
+}
+
+func (r *webAuthnCeremonyRepo) Create(ctx context.Context, c domain.WebAuthnCeremony) error {
+	ttlIndex := mongo.IndexModel{
+		Keys:    bsonx.Doc{{Key: "expiresAt", Value: bsonx.Int32(1)}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := r.Indexes().CreateOne(ctx, ttlIndex)
+	if err != nil {
+		return fmt.Errorf("r.Indexes.CreateOne: %w", err)
+	}
+
+	_, err = r.InsertOne(ctx, c)
+	if err != nil {
+		return fmt.Errorf("r.InsertOne: %w", err)
+	}
+
+	return nil
+}
+
+// Consume deletes ceremony and returns it so challenge of the ceremony can be used only once.
+func (r *webAuthnCeremonyRepo) Consume(ctx context.Context, cid string) (domain.WebAuthnCeremony, error) {
+	var c domain.WebAuthnCeremony
+
+	if err := r.FindOneAndDelete(ctx, bson.M{"_id": cid}).Decode(&c); err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return domain.WebAuthnCeremony{}, fmt.Errorf("r.FindOneAndDelete.Decode: %w", apperrors.ErrWebAuthnCeremonyNotFound)
+		}
+
+		return domain.WebAuthnCeremony{}, fmt.Errorf("r.FindOneAndDelete.Decode: %w", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/postgres"
+)
+
+const _webAuthnCredentialTable = "webauthn_credentials"
+
+type webAuthnCredentialRepo struct {
+	*postgres.Postgres
+}
+
+func NewWebAuthnCredentialRepo(pg *postgres.Postgres) *webAuthnCredentialRepo {
+	return &webAuthnCredentialRepo{pg}
+}
+
+func (r *webAuthnCredentialRepo) Create(ctx context.Context, c domain.WebAuthnCredential) error {
+	sql, args, err := r.Builder.
+		Insert(_webAuthnCredentialTable).
+		Columns("id, account_id, name, public_key, sign_count, created_at").
+		Values(c.ID, c.AccountID, c.Name, c.PublicKey, int64(c.SignCount), c.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Insert: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+
+		if errors.As(err, &pgErr) {
+
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrWebAuthnCredentialAlreadyExist)
+			}
+		}
+
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webAuthnCredentialRepo) FindByID(ctx context.Context, id string) (domain.WebAuthnCredential, error) {
+	sql, args, err := r.Builder.
+		Select("account_id, name, public_key, sign_count, created_at, last_used_at").
+		From(_webAuthnCredentialTable).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return domain.WebAuthnCredential{}, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	c := domain.WebAuthnCredential{ID: id}
+
+	var signCount int64
+
+	if err = r.Pool.QueryRow(ctx, sql, args...).Scan(
+		&c.AccountID,
+		&c.Name,
+		&c.PublicKey,
+		&signCount,
+		&c.CreatedAt,
+		&c.LastUsedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.WebAuthnCredential{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", apperrors.ErrWebAuthnCredentialNotFound)
+		}
+
+		return domain.WebAuthnCredential{}, fmt.Errorf("r.Pool.QueryRow.Scan: %w", err)
+	}
+
+	c.SignCount = uint32(signCount)
+
+	return c, nil
+}
+
+func (r *webAuthnCredentialRepo) FindAll(ctx context.Context, aid string) ([]domain.WebAuthnCredential, error) {
+	sql, args, err := r.Builder.
+		Select("id, name, created_at, last_used_at").
+		From(_webAuthnCredentialTable).
+		Where(sq.Eq{"account_id": aid}).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("r.Builder.Select: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []domain.WebAuthnCredential
+
+	for rows.Next() {
+		c := domain.WebAuthnCredential{AccountID: aid}
+
+		if err = rows.Scan(&c.ID, &c.Name, &c.CreatedAt, &c.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		credentials = append(credentials, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// UpdateSignCount sets signature counter of credential if it is not changed
+// by concurrent login with the same counter.
+func (r *webAuthnCredentialRepo) UpdateSignCount(ctx context.Context, id string, old, new uint32) error {
+	sql, args, err := r.Builder.
+		Update(_webAuthnCredentialTable).
+		Set("sign_count", int64(new)).
+		Set("last_used_at", time.Now()).
+		Where(sq.Eq{"id": id, "sign_count": int64(old)}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Update: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrWebAuthnVerificationFailed)
+	}
+
+	return nil
+}
+
+func (r *webAuthnCredentialRepo) Delete(ctx context.Context, aid, id string) error {
+	sql, args, err := r.Builder.
+		Delete(_webAuthnCredentialTable).
+		Where(sq.Eq{"id": id, "account_id": aid}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder.Delete: %w", err)
+	}
+
+	ct, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("r.Pool.Exec: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("r.Pool.Exec: %w", apperrors.ErrWebAuthnCredentialNotFound)
+	}
+
+	return nil
+}
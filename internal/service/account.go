@@ -2,28 +2,66 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/ysomad/go-auth-service/config"
 	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/email"
+	"github.com/ysomad/go-auth-service/pkg/password"
+	"github.com/ysomad/go-auth-service/pkg/utils"
 )
 
 type accountService struct {
-	cfg     *config.Config
-	repo    AccountRepo
-	session Session
+	cfg               *config.Config
+	repo              AccountRepo
+	verificationRepo  VerificationRepo
+	passwordResetRepo PasswordResetRepo
+	emailChangeRepo   EmailChangeRepo
+	restoreRepo       AccountRestoreRepo
+	identityRepo      IdentityRepo
+	session           Session
+	audit             Audit
+	email             email.Sender
+	hasher            *password.Hasher
+	pepper            *password.Pepper
+	policy            *password.Policy
 }
 
-func NewAccountService(cfg *config.Config, r AccountRepo, s Session) *accountService {
+func NewAccountService(cfg *config.Config, r AccountRepo, vr VerificationRepo, pr PasswordResetRepo,
+	ecr EmailChangeRepo, ar AccountRestoreRepo, ir IdentityRepo, s Session, a Audit, e email.Sender,
+	h *password.Hasher, p *password.Pepper, pp *password.Policy) *accountService {
+
 	return &accountService{
-		cfg:     cfg,
-		repo:    r,
-		session: s,
+		cfg:               cfg,
+		repo:              r,
+		verificationRepo:  vr,
+		passwordResetRepo: pr,
+		emailChangeRepo:   ecr,
+		restoreRepo:       ar,
+		identityRepo:      ir,
+		session:           s,
+		audit:             a,
+		email:             e,
+		hasher:            h,
+		pepper:            p,
+		policy:            pp,
 	}
 }
 
 func (s *accountService) Create(ctx context.Context, a domain.Account) (string, error) {
-	if err := a.GeneratePasswordHash(); err != nil {
+	// generated password is random and never typed by user, policy rules and breach lookup do not apply to it
+	if !a.PasswordGenerated {
+		if err := s.policy.Check(a.Password, a.Username, a.Email); err != nil {
+			return "", fmt.Errorf("accountService - Create - s.policy.Check: %w", err)
+		}
+	}
+
+	if err := a.GeneratePasswordHash(s.hasher, s.pepper); err != nil {
 		return "", fmt.Errorf("accountService - Create - acc.GeneratePasswordHash: %w", err)
 	}
 
@@ -32,6 +70,12 @@ func (s *accountService) Create(ctx context.Context, a domain.Account) (string,
 		return "", fmt.Errorf("accountService - Create - s.repo.Create: %w", err)
 	}
 
+	if !a.Verified {
+		if err = s.sendVerification(ctx, aid, a.Email); err != nil {
+			return "", fmt.Errorf("accountService - Create - s.sendVerification: %w", err)
+		}
+	}
+
 	return aid, nil
 }
 
@@ -53,6 +97,95 @@ func (s *accountService) GetByEmail(ctx context.Context, email string) (domain.A
 	return acc, nil
 }
 
+func (s *accountService) GetByUsername(ctx context.Context, username string) (domain.Account, error) {
+	acc, err := s.repo.FindByUsername(ctx, username)
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("accountService - GetByUsername - s.repo.FindByUsername: %w", err)
+	}
+
+	return acc, nil
+}
+
+// Availability represents data transfer object with availability of account fields,
+// nil means field is not checked.
+type Availability struct {
+	Username *bool
+	Email    *bool
+}
+
+func (s *accountService) CheckAvailability(ctx context.Context, username, email string) (Availability, error) {
+	var a Availability
+
+	if username != "" {
+		exists, err := s.repo.Exists(ctx, "username", username)
+		if err != nil {
+			return Availability{}, fmt.Errorf("accountService - CheckAvailability - s.repo.Exists: %w", err)
+		}
+
+		available := !exists
+		a.Username = &available
+	}
+
+	if email != "" {
+		exists, err := s.repo.Exists(ctx, "email", email)
+		if err != nil {
+			return Availability{}, fmt.Errorf("accountService - CheckAvailability - s.repo.Exists: %w", err)
+		}
+
+		available := !exists
+		a.Email = &available
+	}
+
+	return a, nil
+}
+
+func (s *accountService) GetArchivedByEmail(ctx context.Context, email string) (domain.Account, error) {
+	acc, err := s.repo.FindArchivedByEmail(ctx, email)
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("accountService - GetArchivedByEmail - s.repo.FindArchivedByEmail: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (s *accountService) GetArchivedByID(ctx context.Context, aid string) (domain.Account, error) {
+	acc, err := s.repo.FindArchivedByID(ctx, aid)
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("accountService - GetArchivedByID - s.repo.FindArchivedByID: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (s *accountService) GetArchivedByUsername(ctx context.Context, username string) (domain.Account, error) {
+	acc, err := s.repo.FindArchivedByUsername(ctx, username)
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("accountService - GetArchivedByUsername - s.repo.FindArchivedByUsername: %w", err)
+	}
+
+	return acc, nil
+}
+
+func (s *accountService) Update(ctx context.Context, a domain.Account) (domain.Account, error) {
+	if err := s.repo.Update(ctx, a); err != nil {
+		if errors.Is(err, apperrors.ErrAccountVersionConflict) {
+			// version conflict is reported for not existing account too
+			if _, ferr := s.repo.FindByID(ctx, a.ID); ferr != nil {
+				return domain.Account{}, fmt.Errorf("accountService - Update - s.repo.FindByID: %w", ferr)
+			}
+		}
+
+		return domain.Account{}, fmt.Errorf("accountService - Update - s.repo.Update: %w", err)
+	}
+
+	acc, err := s.repo.FindByID(ctx, a.ID)
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("accountService - Update - s.repo.FindByID: %w", err)
+	}
+
+	return acc, nil
+}
+
 func (s *accountService) Delete(ctx context.Context, aid, sid string) error {
 	if err := s.repo.Archive(ctx, aid, true); err != nil {
 		return fmt.Errorf("accountService - Archive - s.repo.Archive: %w", err)
@@ -62,11 +195,461 @@ func (s *accountService) Delete(ctx context.Context, aid, sid string) error {
 		return fmt.Errorf("accountService - Archive - s.session.TerminateAll: %w", err)
 	}
 
+	if err := s.audit.Record(ctx, aid, domain.AuditAccountArchived, nil); err != nil {
+		return fmt.Errorf("accountService - Archive - s.audit.Record: %w", err)
+	}
+
 	return nil
 }
 
 func (s *accountService) Verify(ctx context.Context, code string) error {
-	panic("implement")
+	v, err := s.verificationRepo.FindByCode(ctx, utils.SHA256(code))
+	if err != nil {
+		return fmt.Errorf("accountService - Verify - s.verificationRepo.FindByCode: %w", err)
+	}
+
+	if v.Expired() {
+		return fmt.Errorf("accountService - Verify: %w", apperrors.ErrVerificationCodeExpired)
+	}
+
+	if err = s.repo.Verify(ctx, v.AccountID); err != nil {
+		return fmt.Errorf("accountService - Verify - s.repo.Verify: %w", err)
+	}
+
+	if err = s.verificationRepo.Delete(ctx, v.AccountID); err != nil {
+		return fmt.Errorf("accountService - Verify - s.verificationRepo.Delete: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) ResendVerification(ctx context.Context, email string) error {
+	a, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("accountService - ResendVerification - s.repo.FindByEmail: %w", err)
+	}
+
+	if a.Verified {
+		return fmt.Errorf("accountService - ResendVerification: %w", apperrors.ErrAccountAlreadyVerified)
+	}
+
+	v, err := s.verificationRepo.FindByAccountID(ctx, a.ID)
+	if err != nil && !errors.Is(err, apperrors.ErrVerificationCodeNotFound) {
+		return fmt.Errorf("accountService - ResendVerification - s.verificationRepo.FindByAccountID: %w", err)
+	}
+
+	if err == nil && time.Since(v.CreatedAt) < s.cfg.Verification.ResendCooldown {
+		return fmt.Errorf("accountService - ResendVerification: %w", apperrors.ErrVerificationCooldown)
+	}
+
+	if err = s.sendVerification(ctx, a.ID, a.Email); err != nil {
+		return fmt.Errorf("accountService - ResendVerification - s.sendVerification: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) RequestPasswordReset(ctx context.Context, email string) error {
+	a, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("accountService - RequestPasswordReset - s.repo.FindByEmail: %w", err)
+	}
+
+	pr, err := domain.NewPasswordReset(a.ID, s.cfg.PasswordReset.TokenTTL)
+	if err != nil {
+		return fmt.Errorf("accountService - RequestPasswordReset - domain.NewPasswordReset: %w", err)
+	}
+
+	if err = s.passwordResetRepo.Create(ctx, pr); err != nil {
+		return fmt.Errorf("accountService - RequestPasswordReset - s.passwordResetRepo.Create: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"Use the following token to reset your password: %s\n\n"+
+			"The token expires at %s. If you did not request password reset, ignore this email.",
+		pr.Token,
+		pr.ExpiresAt.Format(time.RFC1123),
+	)
+
+	if err = s.email.Send(ctx, a.Email, "Reset your password", body); err != nil {
+		return fmt.Errorf("accountService - RequestPasswordReset - s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) ResetPassword(ctx context.Context, token, password string) error {
+	// Token is consumed only after password is accepted by policy
+	// to let user try another password with the same token.
+	pr, err := s.passwordResetRepo.Find(ctx, utils.SHA256(token))
+	if err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.passwordResetRepo.Find: %w", err)
+	}
+
+	if pr.Expired() {
+		return fmt.Errorf("accountService - ResetPassword: %w", apperrors.ErrPasswordResetTokenExpired)
+	}
+
+	a, err := s.repo.FindByID(ctx, pr.AccountID)
+	if err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.repo.FindByID: %w", err)
+	}
+
+	if err = s.policy.Check(password, a.Username, a.Email); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.policy.Check: %w", err)
+	}
+
+	if _, err = s.passwordResetRepo.Consume(ctx, pr.TokenHash); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.passwordResetRepo.Consume: %w", err)
+	}
+
+	a.Password = password
+
+	if err = a.GeneratePasswordHash(s.hasher, s.pepper); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - a.GeneratePasswordHash: %w", err)
+	}
+
+	if err = s.repo.UpdatePassword(ctx, a.ID, a.PasswordHash, a.PasswordPepperVersion); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.repo.UpdatePassword: %w", err)
+	}
+
+	if err = s.passwordResetRepo.DeleteAll(ctx, a.ID); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.passwordResetRepo.DeleteAll: %w", err)
+	}
+
+	if err = s.session.TerminateAll(ctx, a.ID, ""); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.session.TerminateAll: %w", err)
+	}
+
+	if err = s.audit.Record(ctx, a.ID, domain.AuditPasswordReset, nil); err != nil {
+		return fmt.Errorf("accountService - ResetPassword - s.audit.Record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) ChangePassword(ctx context.Context, aid, sid, oldPassword, newPassword string) error {
+	a, err := s.repo.FindByID(ctx, aid)
+	if err != nil {
+		return fmt.Errorf("accountService - ChangePassword - s.repo.FindByID: %w", err)
+	}
+
+	a.Password = oldPassword
+
+	if err = a.CompareHashAndPassword(s.hasher, s.pepper); err != nil {
+		return fmt.Errorf("accountService - ChangePassword - a.CompareHashAndPassword: %w", err)
+	}
+
+	if err = s.policy.Check(newPassword, a.Username, a.Email); err != nil {
+		return fmt.Errorf("accountService - ChangePassword - s.policy.Check: %w", err)
+	}
+
+	a.Password = newPassword
+
+	if err = a.GeneratePasswordHash(s.hasher, s.pepper); err != nil {
+		return fmt.Errorf("accountService - ChangePassword - a.GeneratePasswordHash: %w", err)
+	}
+
+	if err = s.repo.UpdatePassword(ctx, a.ID, a.PasswordHash, a.PasswordPepperVersion); err != nil {
+		return fmt.Errorf("accountService - ChangePassword - s.repo.UpdatePassword: %w", err)
+	}
+
+	if err = s.session.TerminateAll(ctx, a.ID, sid); err != nil {
+		return fmt.Errorf("accountService - ChangePassword - s.session.TerminateAll: %w", err)
+	}
+
+	if err = s.audit.Record(ctx, a.ID, domain.AuditPasswordChanged, nil); err != nil {
+		return fmt.Errorf("accountService - ChangePassword - s.audit.Record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) VerifyPassword(ctx context.Context, a domain.Account, password string) error {
+	a.Password = password
+
+	if err := a.CompareHashAndPassword(s.hasher, s.pepper); err != nil {
+		return fmt.Errorf("accountService - VerifyPassword - a.CompareHashAndPassword: %w", err)
+	}
+
+	if !s.hasher.NeedsRehash(a.PasswordHash) && !s.pepper.Stale(a.PasswordPepperVersion) {
+		return nil
+	}
+
+	oldHash := a.PasswordHash
+
+	if err := a.GeneratePasswordHash(s.hasher, s.pepper); err != nil {
+		return fmt.Errorf("accountService - VerifyPassword - a.GeneratePasswordHash: %w", err)
+	}
+
+	if err := s.repo.RehashPassword(ctx, a.ID, oldHash, a.PasswordHash, a.PasswordPepperVersion); err != nil {
+		return fmt.Errorf("accountService - VerifyPassword - s.repo.RehashPassword: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) RequestEmailChange(ctx context.Context, aid, email string) error {
+	a, err := s.repo.FindByID(ctx, aid)
+	if err != nil {
+		return fmt.Errorf("accountService - RequestEmailChange - s.repo.FindByID: %w", err)
+	}
+
+	if strings.EqualFold(a.Email, email) {
+		return fmt.Errorf("accountService - RequestEmailChange: %w", apperrors.ErrEmailChangeSameEmail)
+	}
+
+	_, err = s.repo.FindByEmail(ctx, email)
+	if err == nil {
+		return fmt.Errorf("accountService - RequestEmailChange: %w", apperrors.ErrAccountAlreadyExist)
+	}
+
+	if !errors.Is(err, apperrors.ErrAccountNotFound) {
+		return fmt.Errorf("accountService - RequestEmailChange - s.repo.FindByEmail: %w", err)
+	}
+
+	ec, err := domain.NewEmailChange(aid, a.Email, email, s.cfg.EmailChange.TokenTTL, s.cfg.EmailChange.RevertTTL)
+	if err != nil {
+		return fmt.Errorf("accountService - RequestEmailChange - domain.NewEmailChange: %w", err)
+	}
+
+	if err = s.emailChangeRepo.Save(ctx, ec); err != nil {
+		return fmt.Errorf("accountService - RequestEmailChange - s.emailChangeRepo.Save: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"To confirm change of your account email follow the link: %s\n\nThe link expires at %s.",
+		tokenURL(s.cfg.EmailChange.ConfirmURL, ec.Token),
+		ec.ExpiresAt.Format(time.RFC1123),
+	)
+
+	if err = s.email.Send(ctx, ec.NewEmail, "Confirm your new email", body); err != nil {
+		return fmt.Errorf("accountService - RequestEmailChange - s.email.Send: %w", err)
+	}
+
+	body = fmt.Sprintf(
+		"Email of your account is requested to be changed to %s.\n\n"+
+			"If it wasn't you, follow the link to cancel the change and log out all devices: %s",
+		ec.NewEmail,
+		tokenURL(s.cfg.EmailChange.RevertURL, ec.RevertToken),
+	)
+
+	if err = s.email.Send(ctx, ec.OldEmail, "Your email is being changed", body); err != nil {
+		return fmt.Errorf("accountService - RequestEmailChange - s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) ConfirmEmailChange(ctx context.Context, token string) error {
+	ec, err := s.emailChangeRepo.FindByToken(ctx, utils.SHA256(token))
+	if err != nil {
+		return fmt.Errorf("accountService - ConfirmEmailChange - s.emailChangeRepo.FindByToken: %w", err)
+	}
+
+	if ec.Confirmed {
+		return fmt.Errorf("accountService - ConfirmEmailChange: %w", apperrors.ErrEmailChangeNotFound)
+	}
+
+	if ec.Expired() {
+		return fmt.Errorf("accountService - ConfirmEmailChange: %w", apperrors.ErrEmailChangeExpired)
+	}
+
+	if err = s.repo.UpdateEmail(ctx, ec.AccountID, ec.NewEmail); err != nil {
+		return fmt.Errorf("accountService - ConfirmEmailChange - s.repo.UpdateEmail: %w", err)
+	}
+
+	if err = s.emailChangeRepo.Confirm(ctx, ec.AccountID); err != nil {
+		return fmt.Errorf("accountService - ConfirmEmailChange - s.emailChangeRepo.Confirm: %w", err)
+	}
+
+	meta := map[string]string{"oldEmail": ec.OldEmail, "newEmail": ec.NewEmail}
+
+	if err = s.audit.Record(ctx, ec.AccountID, domain.AuditEmailChanged, meta); err != nil {
+		return fmt.Errorf("accountService - ConfirmEmailChange - s.audit.Record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) RevertEmailChange(ctx context.Context, token string) error {
+	ec, err := s.emailChangeRepo.FindByRevertToken(ctx, utils.SHA256(token))
+	if err != nil {
+		return fmt.Errorf("accountService - RevertEmailChange - s.emailChangeRepo.FindByRevertToken: %w", err)
+	}
+
+	if ec.RevertExpired() {
+		return fmt.Errorf("accountService - RevertEmailChange: %w", apperrors.ErrEmailChangeExpired)
+	}
+
+	if ec.Confirmed {
+		if err = s.repo.UpdateEmail(ctx, ec.AccountID, ec.OldEmail); err != nil {
+			return fmt.Errorf("accountService - RevertEmailChange - s.repo.UpdateEmail: %w", err)
+		}
+
+		if err = s.session.TerminateAll(ctx, ec.AccountID, ""); err != nil {
+			return fmt.Errorf("accountService - RevertEmailChange - s.session.TerminateAll: %w", err)
+		}
+	}
+
+	if err = s.emailChangeRepo.Delete(ctx, ec.AccountID); err != nil {
+		return fmt.Errorf("accountService - RevertEmailChange - s.emailChangeRepo.Delete: %w", err)
+	}
+
+	meta := map[string]string{"oldEmail": ec.OldEmail, "newEmail": ec.NewEmail}
+
+	if err = s.audit.Record(ctx, ec.AccountID, domain.AuditEmailChangeReverted, meta); err != nil {
+		return fmt.Errorf("accountService - RevertEmailChange - s.audit.Record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) RequestRestore(ctx context.Context, email string) error {
+	a, err := s.repo.FindArchivedByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("accountService - RequestRestore - s.repo.FindArchivedByEmail: %w", err)
+	}
+
+	if !a.Restorable(s.cfg.AccountRestore.GracePeriod) {
+		return fmt.Errorf("accountService - RequestRestore: %w", apperrors.ErrAccountRestorePeriodExpired)
+	}
+
+	ar, err := domain.NewAccountRestore(a.ID, s.cfg.AccountRestore.TokenTTL)
+	if err != nil {
+		return fmt.Errorf("accountService - RequestRestore - domain.NewAccountRestore: %w", err)
+	}
+
+	if err = s.restoreRepo.Create(ctx, ar); err != nil {
+		return fmt.Errorf("accountService - RequestRestore - s.restoreRepo.Create: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"To restore your account follow the link: %s\n\nThe link expires at %s.",
+		tokenURL(s.cfg.AccountRestore.URL, ar.Token),
+		ar.ExpiresAt.Format(time.RFC1123),
+	)
+
+	if err = s.email.Send(ctx, a.Email, "Restore your account", body); err != nil {
+		return fmt.Errorf("accountService - RequestRestore - s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) Restore(ctx context.Context, token string) error {
+	ar, err := s.restoreRepo.Consume(ctx, utils.SHA256(token))
+	if err != nil {
+		return fmt.Errorf("accountService - Restore - s.restoreRepo.Consume: %w", err)
+	}
+
+	if ar.Expired() {
+		return fmt.Errorf("accountService - Restore: %w", apperrors.ErrAccountRestoreTokenExpired)
+	}
+
+	if err = s.Reactivate(ctx, ar.AccountID); err != nil {
+		return fmt.Errorf("accountService - Restore - s.Reactivate: %w", err)
+	}
+
+	return nil
+}
+
+func (s *accountService) Reactivate(ctx context.Context, aid string) error {
+	a, err := s.repo.FindArchivedByID(ctx, aid)
+	if err != nil {
+		return fmt.Errorf("accountService - Reactivate - s.repo.FindArchivedByID: %w", err)
+	}
+
+	if !a.Restorable(s.cfg.AccountRestore.GracePeriod) {
+		return fmt.Errorf("accountService - Reactivate: %w", apperrors.ErrAccountRestorePeriodExpired)
+	}
+
+	if err = s.repo.Archive(ctx, aid, false); err != nil {
+		return fmt.Errorf("accountService - Reactivate - s.repo.Archive: %w", err)
+	}
+
+	if err = s.restoreRepo.DeleteAll(ctx, aid); err != nil {
+		return fmt.Errorf("accountService - Reactivate - s.restoreRepo.DeleteAll: %w", err)
+	}
+
+	if err = s.audit.Record(ctx, aid, domain.AuditAccountRestored, nil); err != nil {
+		return fmt.Errorf("accountService - Reactivate - s.audit.Record: %w", err)
+	}
 
 	return nil
 }
+
+func (s *accountService) Export(ctx context.Context, aid string) (domain.AccountExport, error) {
+	a, err := s.repo.FindByID(ctx, aid)
+	if err != nil {
+		return domain.AccountExport{}, fmt.Errorf("accountService - Export - s.repo.FindByID: %w", err)
+	}
+
+	sessions, err := s.session.GetAll(ctx, aid)
+	if err != nil {
+		return domain.AccountExport{}, fmt.Errorf("accountService - Export - s.session.GetAll: %w", err)
+	}
+
+	identities, err := s.identityRepo.FindAll(ctx, aid)
+	if err != nil {
+		return domain.AccountExport{}, fmt.Errorf("accountService - Export - s.identityRepo.FindAll: %w", err)
+	}
+
+	if err = s.audit.Record(ctx, aid, domain.AuditAccountExported, nil); err != nil {
+		return domain.AccountExport{}, fmt.Errorf("accountService - Export - s.audit.Record: %w", err)
+	}
+
+	records, err := s.audit.GetAll(ctx, aid)
+	if err != nil {
+		return domain.AccountExport{}, fmt.Errorf("accountService - Export - s.audit.GetAll: %w", err)
+	}
+
+	return domain.AccountExport{
+		Account:    a,
+		Sessions:   sessions,
+		Identities: identities,
+		Audit:      records,
+		ExportedAt: time.Now(),
+	}, nil
+}
+
+// private methods ----------------------------------------------------------------------------------------------------
+
+// sendVerification creates new verification code for account, replacing previous one,
+// and sends it to given email.
+func (s *accountService) sendVerification(ctx context.Context, aid, to string) error {
+	v, err := domain.NewVerification(aid, s.cfg.Verification.CodeTTL)
+	if err != nil {
+		return fmt.Errorf("domain.NewVerification: %w", err)
+	}
+
+	if err = s.verificationRepo.Save(ctx, v); err != nil {
+		return fmt.Errorf("s.verificationRepo.Save: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"Your verification code is %s\n\nThe code expires at %s.",
+		v.Code,
+		v.ExpiresAt.Format(time.RFC1123),
+	)
+
+	if err = s.email.Send(ctx, to, "Verify your email", body); err != nil {
+		return fmt.Errorf("s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+// tokenURL returns base URL with token query parameter.
+func tokenURL(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
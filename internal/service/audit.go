@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/internal/domain"
+)
+
+type auditService struct {
+	repo AuditRepo
+}
+
+func NewAuditService(r AuditRepo) *auditService {
+	return &auditService{
+		repo: r,
+	}
+}
+
+func (s *auditService) Record(ctx context.Context, aid, action string, meta map[string]string) error {
+	ar := domain.AuditRecord{
+		AccountID: aid,
+		Action:    action,
+		Metadata:  meta,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, ar); err != nil {
+		return fmt.Errorf("auditService - Record - s.repo.Create: %w", err)
+	}
+
+	return nil
+}
+
+func (s *auditService) GetAll(ctx context.Context, aid string) ([]domain.AuditRecord, error) {
+	records, err := s.repo.FindAll(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("auditService - GetAll - s.repo.FindAll: %w", err)
+	}
+
+	return records, nil
+}
@@ -2,48 +2,145 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ysomad/go-auth-service/config"
 	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/email"
 	"github.com/ysomad/go-auth-service/pkg/jwt"
+	"github.com/ysomad/go-auth-service/pkg/utils"
 )
 
 type authService struct {
-	cfg     *config.Config
-	token   jwt.Token
-	account Account
-	session Session
+	cfg           *config.Config
+	token         jwt.Token
+	account       Account
+	session       Session
+	mfa           MFA
+	guard         LoginGuard
+	magicLinkRepo MagicLinkRepo
+	email         email.Sender
 }
 
-func NewAuthService(cfg *config.Config, t jwt.Token, a Account, s Session) *authService {
+func NewAuthService(cfg *config.Config, t jwt.Token, a Account, s Session, m MFA, g LoginGuard,
+	mr MagicLinkRepo, e email.Sender) *authService {
+
 	return &authService{
-		cfg:     cfg,
-		token:   t,
-		account: a,
-		session: s,
+		cfg:           cfg,
+		token:         t,
+		account:       a,
+		session:       s,
+		mfa:           m,
+		guard:         g,
+		magicLinkRepo: mr,
+		email:         e,
 	}
 }
 
-func (s *authService) EmailLogin(ctx context.Context, email, password string, d Device) (domain.Session, error) {
+// LoginResult represents data transfer object with result of login,
+// challenge is returned instead of session if second factor is required.
+type LoginResult struct {
+	Session   domain.Session
+	Challenge *domain.MFAChallenge
+}
+
+func (s *authService) EmailLogin(ctx context.Context, email, password string, d Device) (LoginResult, error) {
+	res, err := s.guardedLogin(ctx, email, password, providerEmail, d, s.account.GetByEmail, s.account.GetArchivedByEmail)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("authService - EmailLogin - s.guardedLogin: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *authService) UsernameLogin(ctx context.Context, username, password string, d Device) (LoginResult, error) {
+	res, err := s.guardedLogin(
+		ctx,
+		username,
+		password,
+		providerUsername,
+		d,
+		s.account.GetByUsername,
+		s.account.GetArchivedByUsername,
+	)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("authService - UsernameLogin - s.guardedLogin: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *authService) Login(ctx context.Context, login, password string, d Device) (LoginResult, error) {
+	if strings.Contains(login, "@") {
+		return s.EmailLogin(ctx, login, password, d)
+	}
+
+	return s.UsernameLogin(ctx, login, password, d)
+}
+
+func (s *authService) RequestMagicLink(ctx context.Context, email, browserToken string, d Device) error {
 	a, err := s.account.GetByEmail(ctx, email)
 	if err != nil {
-		return domain.Session{}, fmt.Errorf("authService - EmailLogin - s.account.GetByEmail: %w", err)
+		return fmt.Errorf("authService - RequestMagicLink - s.account.GetByEmail: %w", err)
+	}
+
+	l, err := domain.NewMagicLink(a.ID, browserToken, d.UserAgent, d.IP, s.cfg.MagicLink.SigningKey, s.cfg.MagicLink.TokenTTL)
+	if err != nil {
+		return fmt.Errorf("authService - RequestMagicLink - domain.NewMagicLink: %w", err)
+	}
+
+	if err = s.magicLinkRepo.Create(ctx, l); err != nil {
+		return fmt.Errorf("authService - RequestMagicLink - s.magicLinkRepo.Create: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"Follow the link to log in: %s\n\n"+
+			"The link works only in the browser it was requested from and expires at %s. "+
+			"If you did not request it, ignore this email.",
+		tokenURL(s.cfg.MagicLink.URL, l.Token),
+		l.ExpiresAt.Format(time.RFC1123),
+	)
+
+	if err = s.email.Send(ctx, a.Email, "Your login link", body); err != nil {
+		return fmt.Errorf("authService - RequestMagicLink - s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) MagicLinkLogin(ctx context.Context, token, browserToken string, d Device) (LoginResult, error) {
+	if !domain.MagicLinkTokenValid(token, s.cfg.MagicLink.SigningKey) {
+		return LoginResult{}, fmt.Errorf("authService - MagicLinkLogin: %w", apperrors.ErrMagicLinkNotFound)
+	}
+
+	l, err := s.magicLinkRepo.Consume(ctx, utils.SHA256(token), utils.SHA256(browserToken))
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("authService - MagicLinkLogin - s.magicLinkRepo.Consume: %w", err)
 	}
 
-	a.Password = password
+	if l.Expired() {
+		return LoginResult{}, fmt.Errorf("authService - MagicLinkLogin: %w", apperrors.ErrMagicLinkExpired)
+	}
 
-	if err = a.CompareHashAndPassword(); err != nil {
-		return domain.Session{}, fmt.Errorf("authService - EmailLogin - a.CompareHashAndPassword: %w", err)
+	if l.IP != d.IP || l.UserAgent != d.UserAgent {
+		return LoginResult{}, fmt.Errorf("authService - MagicLinkLogin: %w", apperrors.ErrSessionDeviceMismatch)
 	}
 
-	sess, err := s.session.Create(ctx, a.ID, providerEmail, d)
+	a, err := s.account.GetByID(ctx, l.AccountID)
 	if err != nil {
-		return domain.Session{}, fmt.Errorf("authService - EmailLogin - s.session.Create: %w", err)
+		return LoginResult{}, fmt.Errorf("authService - MagicLinkLogin - s.account.GetByID: %w", err)
 	}
 
-	return sess, nil
+	res, err := createSession(ctx, s.mfa, s.account, s.session, a, providerMagicLink, d)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("authService - MagicLinkLogin - createSession: %w", err)
+	}
 
+	return res, nil
 }
 
 func (s *authService) Logout(ctx context.Context, sid string) error {
@@ -54,16 +151,22 @@ func (s *authService) Logout(ctx context.Context, sid string) error {
 	return nil
 }
 
-func (s *authService) NewAccessToken(ctx context.Context, aid, password string) (string, error) {
+func (s *authService) NewAccessToken(ctx context.Context, aid, password string, d Device) (string, error) {
+	if err := s.guard.Reserve(ctx, aid, d.IP); err != nil {
+		return "", fmt.Errorf("authService - NewAccessToken - s.guard.Reserve: %w", err)
+	}
+
 	a, err := s.account.GetByID(ctx, aid)
 	if err != nil {
 		return "", fmt.Errorf("authService - NewAccessToken - s.account.GetByID: %w", err)
 	}
 
-	a.Password = password
+	if err = s.account.VerifyPassword(ctx, a, password); err != nil {
+		return "", fmt.Errorf("authService - NewAccessToken - s.account.VerifyPassword: %w", err)
+	}
 
-	if err := a.CompareHashAndPassword(); err != nil {
-		return "", fmt.Errorf("authService - NewAccessToken - a.CompareHashAndPassword: %w", err)
+	if err = s.guard.Reset(ctx, aid, d.IP); err != nil {
+		return "", fmt.Errorf("authService - NewAccessToken - s.guard.Reset: %w", err)
 	}
 
 	t, err := s.token.New(aid)
@@ -82,3 +185,104 @@ func (s *authService) ParseAccessToken(ctx context.Context, t string) (string, e
 
 	return aid, nil
 }
+
+// private methods ----------------------------------------------------------------------------------------------------
+
+// guardedLogin finds account by login and logs in if neither account nor ip of device is locked out,
+// attempt is reserved before password is compared and attempts of account are reset on success.
+// Attempts are tracked by account id, so the same account reached by username and email shares
+// attempts with access token requests, login itself is tracked only if it is not resolved to account.
+func (s *authService) guardedLogin(ctx context.Context, login, password, provider string, d Device,
+	find, findArchived func(context.Context, string) (domain.Account, error)) (LoginResult, error) {
+
+	a, err := find(ctx, login)
+	if errors.Is(err, apperrors.ErrAccountNotFound) && s.cfg.AccountRestore.OnLogin {
+		a, err = findArchived(ctx, login)
+	}
+
+	if err != nil && !errors.Is(err, apperrors.ErrAccountNotFound) {
+		return LoginResult{}, err
+	}
+
+	key := login
+	if err == nil {
+		key = a.ID
+	}
+
+	if gerr := s.guard.Reserve(ctx, key, d.IP); gerr != nil {
+		return LoginResult{}, fmt.Errorf("s.guard.Reserve: %w", gerr)
+	}
+
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	res, err := s.login(ctx, a, password, provider, d)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if err = s.guard.Reset(ctx, key, d.IP); err != nil {
+		return LoginResult{}, fmt.Errorf("s.guard.Reset: %w", err)
+	}
+
+	return res, nil
+}
+
+// login compares password with account password hash and creates new session of given provider,
+// archived account is restored if its grace period is not expired.
+// If account has second factor enabled mfa challenge is created instead of session.
+func (s *authService) login(ctx context.Context, a domain.Account, password, provider string, d Device) (LoginResult, error) {
+	if err := s.account.VerifyPassword(ctx, a, password); err != nil {
+		return LoginResult{}, fmt.Errorf("s.account.VerifyPassword: %w", err)
+	}
+
+	if s.cfg.Verification.LoginRequired && !a.Verified {
+		return LoginResult{}, apperrors.ErrAccountNotVerified
+	}
+
+	if a.Archive && !a.Restorable(s.cfg.AccountRestore.GracePeriod) {
+		return LoginResult{}, apperrors.ErrAccountNotFound
+	}
+
+	res, err := createSession(ctx, s.mfa, s.account, s.session, a, provider, d)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("createSession: %w", err)
+	}
+
+	return res, nil
+}
+
+// createSession creates new session of given provider for authenticated account
+// or mfa challenge if account has second factor enabled. Archived account is restored
+// before session is created or when the challenge is completed, not by first factor alone.
+func createSession(ctx context.Context, m MFA, ac Account, s Session, a domain.Account, provider string,
+	d Device) (LoginResult, error) {
+
+	enabled, err := m.Enabled(ctx, a.ID)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("m.Enabled: %w", err)
+	}
+
+	if enabled {
+		c, err := m.CreateChallenge(ctx, a, provider, d)
+		if err != nil {
+			return LoginResult{}, fmt.Errorf("m.CreateChallenge: %w", err)
+		}
+
+		return LoginResult{Challenge: &c}, nil
+	}
+
+	if a.Archive {
+		if err = ac.Reactivate(ctx, a.ID); err != nil {
+			return LoginResult{}, fmt.Errorf("ac.Reactivate: %w", err)
+		}
+	}
+
+	sess, err := s.Create(ctx, a.ID, provider, d)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("s.Create: %w", err)
+	}
+
+	return LoginResult{Session: sess}, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/domain"
+)
+
+type erasureService struct {
+	cfg         *config.Config
+	accountRepo AccountRepo
+	session     Session
+}
+
+func NewErasureService(cfg *config.Config, r AccountRepo, s Session) *erasureService {
+	return &erasureService{
+		cfg:         cfg,
+		accountRepo: r,
+		session:     s,
+	}
+}
+
+func (s *erasureService) Purge(ctx context.Context) (int, error) {
+	ids, err := s.accountRepo.FindArchivedBefore(
+		ctx,
+		time.Now().Add(-s.cfg.Erasure.Retention),
+		s.cfg.Erasure.BatchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("erasureService - Purge - s.accountRepo.FindArchivedBefore: %w", err)
+	}
+
+	for i, aid := range ids {
+		if err = s.erase(ctx, aid); err != nil {
+			return i, fmt.Errorf("erasureService - Purge - s.erase: %w", err)
+		}
+	}
+
+	return len(ids), nil
+}
+
+// private methods ----------------------------------------------------------------------------------------------------
+
+// erase deletes account with all its sessions and audit history,
+// account data stored in postgres is deleted by cascade.
+// Erasure itself is recorded in audit in the same transaction as account is deleted,
+// sessions are terminated first since terminating them again is harmless if erasure fails.
+func (s *erasureService) erase(ctx context.Context, aid string) error {
+	if err := s.session.TerminateAll(ctx, aid, ""); err != nil {
+		return fmt.Errorf("s.session.TerminateAll: %w", err)
+	}
+
+	ar := domain.AuditRecord{
+		AccountID: aid,
+		Action:    domain.AuditAccountErased,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.accountRepo.Erase(ctx, aid, ar); err != nil {
+		return fmt.Errorf("s.accountRepo.Erase: %w", err)
+	}
+
+	return nil
+}
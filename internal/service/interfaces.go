@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
 
 	"github.com/ysomad/go-auth-service/internal/domain"
 )
@@ -10,6 +13,8 @@ import (
 type (
 	Account interface {
 		// Create new account, username, email and password should be provided, returns account id.
+		// Password must satisfy password policy unless it is generated. Verification code is delivered
+		// by email sender in background, so signup does not fail if mail server is unavailable.
 		Create(ctx context.Context, a domain.Account) (string, error)
 
 		// GetByID account.
@@ -18,11 +23,72 @@ type (
 		// GetByEmail account.
 		GetByEmail(ctx context.Context, email string) (domain.Account, error)
 
+		// GetByUsername account.
+		GetByUsername(ctx context.Context, username string) (domain.Account, error)
+
+		// CheckAvailability reports whether provided username and email are not taken,
+		// empty values are not checked.
+		CheckAvailability(ctx context.Context, username, email string) (Availability, error)
+
+		// GetArchivedByEmail archived account.
+		GetArchivedByEmail(ctx context.Context, email string) (domain.Account, error)
+
+		// GetArchivedByUsername archived account.
+		GetArchivedByUsername(ctx context.Context, username string) (domain.Account, error)
+
+		// GetArchivedByID archived account.
+		GetArchivedByID(ctx context.Context, aid string) (domain.Account, error)
+
+		// Update account profile if provided version matches current one, returns updated account.
+		Update(ctx context.Context, a domain.Account) (domain.Account, error)
+
 		// Delete sets account IsArchive state to true.
 		Delete(ctx context.Context, aid, sid string) error
 
 		// Verify verifies account using provided code.
 		Verify(ctx context.Context, code string) error
+
+		// ResendVerification sends new verification code to account with given email.
+		ResendVerification(ctx context.Context, email string) error
+
+		// RequestPasswordReset sends password reset token to account with given email.
+		RequestPasswordReset(ctx context.Context, email string) error
+
+		// ResetPassword sets new password to account using password reset token
+		// and terminates all account sessions, token is not consumed if password violates policy.
+		ResetPassword(ctx context.Context, token, password string) error
+
+		// ChangePassword sets new password to account if old one is correct and satisfies policy
+		// and terminates all account sessions excluding current session with id.
+		ChangePassword(ctx context.Context, aid, sid, oldPassword, newPassword string) error
+
+		// VerifyPassword compares password with account password hash and upgrades the hash
+		// if it is made with outdated algorithm, parameters or pepper key.
+		VerifyPassword(ctx context.Context, a domain.Account, password string) error
+
+		// RequestEmailChange sends confirmation token to new email
+		// and notice with revert token to current email of account.
+		RequestEmailChange(ctx context.Context, aid, email string) error
+
+		// ConfirmEmailChange sets new email to account using confirmation token.
+		ConfirmEmailChange(ctx context.Context, token string) error
+
+		// RevertEmailChange cancels pending email change or restores old email if change
+		// is already confirmed, in that case all account sessions are terminated.
+		RevertEmailChange(ctx context.Context, token string) error
+
+		// RequestRestore sends restore token to archived account with given email
+		// if its grace period is not expired.
+		RequestRestore(ctx context.Context, email string) error
+
+		// Restore sets account IsArchive state to false using restore token.
+		Restore(ctx context.Context, token string) error
+
+		// Reactivate sets account IsArchive state to false.
+		Reactivate(ctx context.Context, aid string) error
+
+		// Export returns all personal data stored about account.
+		Export(ctx context.Context, aid string) (domain.AccountExport, error)
 	}
 
 	AccountRepo interface {
@@ -35,34 +101,399 @@ type (
 		// FindByEmail account in DB.
 		FindByEmail(ctx context.Context, email string) (domain.Account, error)
 
+		// FindByUsername account in DB.
+		FindByUsername(ctx context.Context, username string) (domain.Account, error)
+
+		// Exists reports whether any account, including archived ones, has given value in column.
+		Exists(ctx context.Context, column, value string) (bool, error)
+
+		// FindArchivedByID archived account in DB.
+		FindArchivedByID(ctx context.Context, aid string) (domain.Account, error)
+
+		// FindArchivedByEmail archived account in DB.
+		FindArchivedByEmail(ctx context.Context, email string) (domain.Account, error)
+
+		// FindArchivedByUsername archived account in DB.
+		FindArchivedByUsername(ctx context.Context, username string) (domain.Account, error)
+
+		// FindArchivedBefore returns ids of accounts archived before given time.
+		FindArchivedBefore(ctx context.Context, t time.Time, limit int) ([]string, error)
+
 		// Archive sets entity.Account.IsArchive state to provided value.
 		Archive(ctx context.Context, aid string, archive bool) error
+
+		// Erase deletes account permanently with its audit records and creates audit record
+		// of the erasure in one transaction, so erasure is never left unrecorded.
+		Erase(ctx context.Context, aid string, ar domain.AuditRecord) error
+
+		// Verify sets entity.Account.Verified state to true.
+		Verify(ctx context.Context, aid string) error
+
+		// Update account profile fields and increments its version,
+		// account must have the same version as provided one.
+		Update(ctx context.Context, a domain.Account) error
+
+		// UpdatePassword sets new password hash of account set by user and version of pepper applied to password.
+		UpdatePassword(ctx context.Context, aid, passwordHash string, pepperVersion int) error
+
+		// RehashPassword replaces password hash of account with new hash of the same password
+		// if account password hash was not changed since old hash was read.
+		RehashPassword(ctx context.Context, aid, oldHash, newHash string, pepperVersion int) error
+
+		// UpdateEmail sets new verified email of account.
+		UpdateEmail(ctx context.Context, aid, email string) error
+	}
+
+	VerificationRepo interface {
+		// Save creates new verification code or replaces existing one of account.
+		Save(ctx context.Context, v domain.Verification) error
+
+		// FindByAccountID verification code of account.
+		FindByAccountID(ctx context.Context, aid string) (domain.Verification, error)
+
+		// FindByCode finds verification code by its hash.
+		FindByCode(ctx context.Context, codeHash string) (domain.Verification, error)
+
+		// Delete verification code of account.
+		Delete(ctx context.Context, aid string) error
+	}
+
+	PasswordResetRepo interface {
+		// Create new password reset token.
+		Create(ctx context.Context, pr domain.PasswordReset) error
+
+		// Find password reset token by its hash without consuming it.
+		Find(ctx context.Context, tokenHash string) (domain.PasswordReset, error)
+
+		// Consume deletes password reset token by its hash and returns it.
+		Consume(ctx context.Context, tokenHash string) (domain.PasswordReset, error)
+
+		// DeleteAll password reset tokens of account.
+		DeleteAll(ctx context.Context, aid string) error
+	}
+
+	AccountRestoreRepo interface {
+		// Create new account restore token.
+		Create(ctx context.Context, ar domain.AccountRestore) error
+
+		// Consume deletes account restore token by its hash and returns it.
+		Consume(ctx context.Context, tokenHash string) (domain.AccountRestore, error)
+
+		// DeleteAll account restore tokens of account.
+		DeleteAll(ctx context.Context, aid string) error
+	}
+
+	EmailChangeRepo interface {
+		// Save creates new pending email change or replaces existing one of account.
+		Save(ctx context.Context, ec domain.EmailChange) error
+
+		// FindByToken finds email change by confirmation token hash.
+		FindByToken(ctx context.Context, tokenHash string) (domain.EmailChange, error)
+
+		// FindByRevertToken finds email change by revert token hash.
+		FindByRevertToken(ctx context.Context, tokenHash string) (domain.EmailChange, error)
+
+		// Confirm marks email change of account as confirmed.
+		Confirm(ctx context.Context, aid string) error
+
+		// Delete email change of account.
+		Delete(ctx context.Context, aid string) error
+	}
+
+	Audit interface {
+		// Record saves action performed on account with optional metadata.
+		Record(ctx context.Context, aid, action string, meta map[string]string) error
+
+		// GetAll audit records of account.
+		GetAll(ctx context.Context, aid string) ([]domain.AuditRecord, error)
+	}
+
+	AuditRepo interface {
+		// Create new audit record in DB.
+		Create(ctx context.Context, ar domain.AuditRecord) error
+
+		// FindAll audit records of account.
+		FindAll(ctx context.Context, aid string) ([]domain.AuditRecord, error)
+	}
+
+	Erasure interface {
+		// Purge permanently deletes accounts archived longer than retention period
+		// with all their data, returns number of erased accounts.
+		Purge(ctx context.Context) (int, error)
 	}
 
 	Auth interface {
-		// EmailLogin creates new session using provided account email and password.
-		EmailLogin(ctx context.Context, email, password string, d Device) (domain.Session, error)
+		// EmailLogin creates new session using provided account email and password,
+		// mfa challenge is created instead if account has second factor enabled.
+		EmailLogin(ctx context.Context, email, password string, d Device) (LoginResult, error)
+
+		// UsernameLogin creates new session using provided account username and password,
+		// mfa challenge is created instead if account has second factor enabled.
+		UsernameLogin(ctx context.Context, username, password string, d Device) (LoginResult, error)
+
+		// Login creates new session using provided account email or username and password,
+		// mfa challenge is created instead if account has second factor enabled.
+		Login(ctx context.Context, login, password string, d Device) (LoginResult, error)
+
+		// RequestMagicLink sends single-use login link to account email,
+		// the link can be used only from device and browser with given browser token.
+		RequestMagicLink(ctx context.Context, email, browserToken string, d Device) error
+
+		// MagicLinkLogin creates new session using magic link token,
+		// mfa challenge is created instead if account has second factor enabled.
+		MagicLinkLogin(ctx context.Context, token, browserToken string, d Device) (LoginResult, error)
 
 		// Logout logs out session by id.
 		Logout(ctx context.Context, sid string) error
 
 		// NewAccessToken generates JWT token which must be used to perform protected operations.
-		NewAccessToken(ctx context.Context, aid, password string) (string, error)
+		NewAccessToken(ctx context.Context, aid, password string, d Device) (string, error)
 
 		// ParseAccessToken parses and validates JWT access token, returns subject from payload.
 		ParseAccessToken(ctx context.Context, t string) (string, error)
 	}
 
+	LoginGuard interface {
+		// Reserve counts attempt made with login from ip before credentials are compared
+		// and returns LockoutError if login or ip is locked out after too many attempts,
+		// login is account id or login which is not resolved to account. Reserved attempt
+		// is counted as failed unless it is reset.
+		Reserve(ctx context.Context, login, ip string) error
+
+		// Reset attempts of login and attempt reserved for ip after successful attempt.
+		Reset(ctx context.Context, login, ip string) error
+	}
+
+	LoginAttemptRepo interface {
+		// Increment number of failed attempts by key and prolongs their ttl,
+		// returns attempts after increment read in the same transaction.
+		Increment(ctx context.Context, key string, ttl time.Duration) (domain.LoginAttempts, error)
+
+		// Decrement number of failed attempts by key if there are any.
+		Decrement(ctx context.Context, key string) error
+
+		// Lock key until given time if it is not locked yet, reports whether key is locked by the call.
+		Lock(ctx context.Context, key string, until time.Time) (bool, error)
+
+		// Delete failed attempts by key.
+		Delete(ctx context.Context, key string) error
+	}
+
+	MagicLinkRepo interface {
+		// Create new magic link.
+		Create(ctx context.Context, l domain.MagicLink) error
+
+		// Consume finds magic link by token hash and browser token hash and deletes it.
+		Consume(ctx context.Context, tokenHash, browserTokenHash string) (domain.MagicLink, error)
+
+		// DeleteAll magic links of account.
+		DeleteAll(ctx context.Context, aid string) error
+	}
+
+	MFA interface {
+		// EnrollTOTP generates new unconfirmed totp secret and recovery codes for account.
+		EnrollTOTP(ctx context.Context, aid string) (TOTPEnrollment, error)
+
+		// ConfirmTOTP enables totp second factor of account if code is valid.
+		ConfirmTOTP(ctx context.Context, aid, code string) error
+
+		// DisableTOTP deletes totp second factor and recovery codes of account.
+		DisableTOTP(ctx context.Context, aid string) error
+
+		// EnableEmailOTP enables email one-time passcode second factor of account
+		// and returns new recovery codes.
+		EnableEmailOTP(ctx context.Context, aid string) ([]string, error)
+
+		// DisableEmailOTP disables email one-time passcode second factor of account.
+		DisableEmailOTP(ctx context.Context, aid string) error
+
+		// RegenerateRecoveryCodes replaces recovery codes of account with new ones.
+		RegenerateRecoveryCodes(ctx context.Context, aid string) ([]string, error)
+
+		// Enabled reports whether account has second factor enabled.
+		Enabled(ctx context.Context, aid string) (bool, error)
+
+		// CreateChallenge creates challenge which must be completed with second factor
+		// from the same device to create session of given provider,
+		// archived account is restored when the challenge is completed.
+		CreateChallenge(ctx context.Context, a domain.Account, provider string, d Device) (domain.MFAChallenge, error)
+
+		// VerifyTOTP completes challenge with totp code and creates session.
+		VerifyTOTP(ctx context.Context, cid, code string, d Device) (domain.Session, error)
+
+		// SendEmailOTP sends one-time passcode to email of challenge account.
+		SendEmailOTP(ctx context.Context, cid string, d Device) error
+
+		// VerifyEmailOTP completes challenge with one-time passcode sent by email and creates session.
+		VerifyEmailOTP(ctx context.Context, cid, code string, d Device) (domain.Session, error)
+
+		// VerifyRecoveryCode completes challenge with recovery code instead of second factor
+		// and creates session, account owner is notified about use of the code.
+		VerifyRecoveryCode(ctx context.Context, cid, code string, d Device) (domain.Session, error)
+	}
+
+	TOTPRepo interface {
+		// Save creates new unconfirmed totp or replaces existing unconfirmed one.
+		Save(ctx context.Context, t domain.TOTP) error
+
+		// FindByAccountID totp of account.
+		FindByAccountID(ctx context.Context, aid string) (domain.TOTP, error)
+
+		// Confirm marks totp of account as confirmed with used time step.
+		Confirm(ctx context.Context, aid string, step int64) error
+
+		// UseStep sets last used time step of totp, step must be greater than current one.
+		UseStep(ctx context.Context, aid string, step int64) error
+
+		// Delete totp of account.
+		Delete(ctx context.Context, aid string) error
+	}
+
+	EmailOTPRepo interface {
+		// Enable email one-time passcode second factor of account.
+		Enable(ctx context.Context, aid string) error
+
+		// Enabled reports whether account has email one-time passcode second factor enabled.
+		Enabled(ctx context.Context, aid string) (bool, error)
+
+		// Disable email one-time passcode second factor of account.
+		Disable(ctx context.Context, aid string) error
+	}
+
+	RecoveryCodeRepo interface {
+		// Replace all recovery codes of account with given ones.
+		Replace(ctx context.Context, aid string, codes []domain.RecoveryCode) error
+
+		// Use marks unused recovery code of account as used.
+		Use(ctx context.Context, aid, codeHash string) error
+
+		// CountUnused returns number of unused recovery codes of account.
+		CountUnused(ctx context.Context, aid string) (int, error)
+
+		// DeleteAll recovery codes of account.
+		DeleteAll(ctx context.Context, aid string) error
+	}
+
+	MFAChallengeRepo interface {
+		// Create new mfa challenge in DB.
+		Create(ctx context.Context, c domain.MFAChallenge) error
+
+		// FindByID mfa challenge.
+		FindByID(ctx context.Context, cid string) (domain.MFAChallenge, error)
+
+		// SetEmailCode sets hash and expiration time of email code if previous one is sent before given time.
+		SetEmailCode(ctx context.Context, cid, codeHash string, expiresAt, sentBefore time.Time) error
+
+		// ClaimAttempt atomically increments number of attempts of mfa challenge if it is less than max,
+		// returns ErrMFAChallengeNotFound if challenge has no attempts left.
+		ClaimAttempt(ctx context.Context, cid string, max int) error
+
+		// Delete mfa challenge by id.
+		Delete(ctx context.Context, cid string) error
+	}
+
 	SocialAuth interface {
 		// AuthorizationURL returns OAuth authorization URL of given provider with
-		// client id, scope and state query parameters.
-		AuthorizationURL(ctx context.Context, provider string) (*url.URL, error)
+		// client id, scope, state and PKCE code challenge query parameters,
+		// state is stored and bound to browser with given token.
+		AuthorizationURL(ctx context.Context, provider, browserToken string) (*url.URL, error)
+
+		// Login handles OAuth2 login via provider from config with authorization code and state
+		// received in the browser which requested authorization url. User is logged in to account
+		// linked to the user identity, if there is no linked account identity is linked to account
+		// with verified email of the user or to created account.
+		// If account has second factor enabled mfa challenge is returned instead of session.
+		Login(ctx context.Context, provider, code, state, browserToken string, d Device) (LoginResult, error)
+
+		// LinkURL returns OAuth authorization URL of given provider to link user identity to account.
+		LinkURL(ctx context.Context, aid, provider, browserToken string) (*url.URL, error)
+
+		// Link links user identity of provider to account with authorization code and state
+		// received in the browser which requested link url.
+		Link(ctx context.Context, aid, provider, code, state, browserToken string) (domain.Identity, error)
+
+		// Unlink deletes identity of provider linked to account if it is not the last login method of account.
+		Unlink(ctx context.Context, aid, provider string) error
+
+		// Identities returns all identities linked to account.
+		Identities(ctx context.Context, aid string) ([]domain.Identity, error)
+	}
+
+	OAuthStateRepo interface {
+		// Create new oauth state.
+		Create(ctx context.Context, s domain.OAuthState) error
+
+		// Consume finds state issued for account and provider to browser by hashes and deletes it.
+		Consume(ctx context.Context, stateHash, aid, provider, browserTokenHash string) (domain.OAuthState, error)
+	}
+
+	IdentityRepo interface {
+		// Create new identity.
+		Create(ctx context.Context, i domain.Identity) error
+
+		// FindByProviderUserID finds identity by provider and id of the user in provider.
+		FindByProviderUserID(ctx context.Context, provider, providerUserID string) (domain.Identity, error)
+
+		// FindAll identities linked to account.
+		FindAll(ctx context.Context, aid string) ([]domain.Identity, error)
+
+		// Delete identity of provider linked to account.
+		Delete(ctx context.Context, aid, provider string) error
+	}
+
+	// SocialProvider gets user of OAuth2 provider.
+	SocialProvider interface {
+		// OAuth2Config returns OAuth2 client configuration of the provider.
+		OAuth2Config(ctx context.Context) (*oauth2.Config, error)
+
+		// Identity returns user of the provider using token received with authorization code.
+		Identity(ctx context.Context, t *oauth2.Token) (SocialIdentity, error)
+	}
+
+	WebAuthn interface {
+		// BeginRegistration starts credential registration ceremony for account.
+		BeginRegistration(ctx context.Context, aid string, d Device) (WebAuthnRegistration, error)
+
+		// FinishRegistration verifies authenticator attestation of registration ceremony
+		// and saves new credential of account.
+		FinishRegistration(ctx context.Context, aid, cid string, att WebAuthnAttestation, d Device) (domain.WebAuthnCredential, error)
+
+		// GetAllCredentials returns credentials registered by account.
+		GetAllCredentials(ctx context.Context, aid string) ([]domain.WebAuthnCredential, error)
+
+		// DeleteCredential of account.
+		DeleteCredential(ctx context.Context, aid, id string) error
+
+		// BeginLogin starts login ceremony, account is identified later by credential.
+		BeginLogin(ctx context.Context, d Device) (domain.WebAuthnCeremony, error)
+
+		// FinishLogin verifies authenticator assertion of login ceremony and creates session.
+		FinishLogin(ctx context.Context, cid string, as WebAuthnAssertion, d Device) (domain.Session, error)
+	}
+
+	WebAuthnCredentialRepo interface {
+		// Create new credential.
+		Create(ctx context.Context, c domain.WebAuthnCredential) error
+
+		// FindByID credential with public key.
+		FindByID(ctx context.Context, id string) (domain.WebAuthnCredential, error)
+
+		// FindAll credentials of account without public keys.
+		FindAll(ctx context.Context, aid string) ([]domain.WebAuthnCredential, error)
+
+		// UpdateSignCount of credential from old to new value.
+		UpdateSignCount(ctx context.Context, id string, old, new uint32) error
+
+		// Delete credential of account.
+		Delete(ctx context.Context, aid, id string) error
+	}
 
-		// GitHubLogin handles OAuth2 login via GitHub.
-		GitHubLogin(ctx context.Context, code string, d Device) (domain.Session, error)
+	WebAuthnCeremonyRepo interface {
+		// Create new ceremony.
+		Create(ctx context.Context, c domain.WebAuthnCeremony) error
 
-		// GoogleLogin handles OAuth2 login via Google.
-		GoogleLogin(ctx context.Context, code string, d Device) (domain.Session, error)
+		// Consume finds ceremony by id and deletes it.
+		Consume(ctx context.Context, cid string) (domain.WebAuthnCeremony, error)
 	}
 
 	Session interface {
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+)
+
+// LockoutError is returned when login or ip is temporarily locked out after too many failed attempts.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", apperrors.ErrTooManyLoginAttempts, e.RetryAfter)
+}
+
+func (e *LockoutError) Unwrap() error {
+	return apperrors.ErrTooManyLoginAttempts
+}
+
+type loginGuard struct {
+	cfg  *config.Config
+	repo LoginAttemptRepo
+}
+
+func NewLoginGuard(cfg *config.Config, r LoginAttemptRepo) *loginGuard {
+	return &loginGuard{
+		cfg:  cfg,
+		repo: r,
+	}
+}
+
+// Reserve counts attempt before credentials are compared, so attempts made in parallel
+// see each other and cannot exceed free attempts. Attempt made after free attempts are exhausted
+// is allowed only if it locks login or ip out with growing delay, attempts made while locked out are counted too.
+func (g *loginGuard) Reserve(ctx context.Context, login, ip string) error {
+	limits := []struct {
+		key  string
+		free int
+	}{
+		{loginAttemptsKey(login), g.cfg.BruteForce.LoginAttempts},
+		{ipAttemptsKey(ip), g.cfg.BruteForce.IPAttempts},
+	}
+
+	for _, l := range limits {
+		a, err := g.repo.Increment(ctx, l.key, g.cfg.BruteForce.Window)
+		if err != nil {
+			return fmt.Errorf("loginGuard - Reserve - g.repo.Increment: %w", err)
+		}
+
+		if a.Locked() {
+			return &LockoutError{RetryAfter: a.RetryAfter()}
+		}
+
+		if a.Failures < l.free {
+			continue
+		}
+
+		until := time.Now().Add(g.backoff(a.Failures - l.free))
+
+		locked, err := g.repo.Lock(ctx, l.key, until)
+		if err != nil {
+			return fmt.Errorf("loginGuard - Reserve - g.repo.Lock: %w", err)
+		}
+
+		// parallel attempt has locked key out first
+		if !locked {
+			return &LockoutError{RetryAfter: time.Until(until)}
+		}
+	}
+
+	return nil
+}
+
+func (g *loginGuard) Reset(ctx context.Context, login, ip string) error {
+	if err := g.repo.Delete(ctx, loginAttemptsKey(login)); err != nil {
+		return fmt.Errorf("loginGuard - Reset - g.repo.Delete: %w", err)
+	}
+
+	if err := g.repo.Decrement(ctx, ipAttemptsKey(ip)); err != nil {
+		return fmt.Errorf("loginGuard - Reset - g.repo.Decrement: %w", err)
+	}
+
+	return nil
+}
+
+// loginAttemptsKey returns key of attempts made with login which is account id
+// or login which is not resolved to account, login is case insensitive.
+func loginAttemptsKey(login string) string {
+	return "login:" + strings.ToLower(login)
+}
+
+func ipAttemptsKey(ip string) string {
+	return "ip:" + ip
+}
+
+// backoff returns lock duration which doubles with each failure
+// after free attempts are exhausted up to max delay.
+func (g *loginGuard) backoff(n int) time.Duration {
+	d := g.cfg.BruteForce.BaseDelay
+
+	for i := 0; i < n && d < g.cfg.BruteForce.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if d > g.cfg.BruteForce.MaxDelay {
+		d = g.cfg.BruteForce.MaxDelay
+	}
+
+	return d
+}
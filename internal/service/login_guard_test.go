@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/repository"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+)
+
+func newTestLoginGuard() *loginGuard {
+	cfg := &config.Config{}
+	cfg.BruteForce.LoginAttempts = 5
+	cfg.BruteForce.IPAttempts = 100
+	cfg.BruteForce.BaseDelay = time.Minute
+	cfg.BruteForce.MaxDelay = time.Hour
+	cfg.BruteForce.Window = time.Hour
+
+	return NewLoginGuard(cfg, repository.NewLoginAttemptMemoryRepo())
+}
+
+func TestLoginGuardParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	g := newTestLoginGuard()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := g.Reserve(ctx, testAccountID, testDevice.IP)
+			if err != nil && !errors.Is(err, apperrors.ErrTooManyLoginAttempts) {
+				t.Errorf("Reserve: %v", err)
+				return
+			}
+
+			if err == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if allowed != g.cfg.BruteForce.LoginAttempts {
+		t.Fatalf("allowed attempts = %d, want %d", allowed, g.cfg.BruteForce.LoginAttempts)
+	}
+}
+
+func TestLoginGuardReset(t *testing.T) {
+	ctx := context.Background()
+	g := newTestLoginGuard()
+
+	for i := 0; i < g.cfg.BruteForce.LoginAttempts-1; i++ {
+		if err := g.Reserve(ctx, testAccountID, testDevice.IP); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+	}
+
+	if err := g.Reset(ctx, testAccountID, testDevice.IP); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	// free attempts are available again after successful attempt
+	for i := 0; i < g.cfg.BruteForce.LoginAttempts; i++ {
+		if err := g.Reserve(ctx, testAccountID, testDevice.IP); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+	}
+
+	var lerr *LockoutError
+	if err := g.Reserve(ctx, testAccountID, testDevice.IP); !errors.As(err, &lerr) {
+		t.Fatalf("Reserve error = %v, want %T", err, lerr)
+	}
+}
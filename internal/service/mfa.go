@@ -0,0 +1,545 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/email"
+	"github.com/ysomad/go-auth-service/pkg/encryption"
+	"github.com/ysomad/go-auth-service/pkg/totp"
+)
+
+// totpSkew is number of time steps before and after current one in which totp code is valid.
+const totpSkew = 1
+
+type mfaService struct {
+	cfg              *config.Config
+	totpRepo         TOTPRepo
+	emailOTPRepo     EmailOTPRepo
+	challengeRepo    MFAChallengeRepo
+	recoveryCodeRepo RecoveryCodeRepo
+	account          Account
+	session          Session
+	audit            Audit
+	cipher           encryption.Cipher
+	email            email.Sender
+}
+
+func NewMFAService(cfg *config.Config, tr TOTPRepo, er EmailOTPRepo, cr MFAChallengeRepo, rr RecoveryCodeRepo,
+	a Account, s Session, au Audit, c encryption.Cipher, e email.Sender) *mfaService {
+
+	return &mfaService{
+		cfg:              cfg,
+		totpRepo:         tr,
+		emailOTPRepo:     er,
+		challengeRepo:    cr,
+		recoveryCodeRepo: rr,
+		account:          a,
+		session:          s,
+		audit:            au,
+		cipher:           c,
+		email:            e,
+	}
+}
+
+// TOTPEnrollment represents data transfer object with totp secret
+// which must be added to authenticator app and recovery codes
+// which can be used if authenticator app is lost.
+type TOTPEnrollment struct {
+	Secret        string
+	URI           string
+	RecoveryCodes []string
+}
+
+func (s *mfaService) EnrollTOTP(ctx context.Context, aid string) (TOTPEnrollment, error) {
+	a, err := s.account.GetByID(ctx, aid)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService - EnrollTOTP - s.account.GetByID: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService - EnrollTOTP - totp.GenerateSecret: %w", err)
+	}
+
+	enc, err := s.cipher.Encrypt([]byte(secret))
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService - EnrollTOTP - s.cipher.Encrypt: %w", err)
+	}
+
+	t := domain.TOTP{
+		AccountID:       aid,
+		EncryptedSecret: enc,
+		CreatedAt:       time.Now(),
+	}
+
+	if err = s.totpRepo.Save(ctx, t); err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService - EnrollTOTP - s.totpRepo.Save: %w", err)
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, aid)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService - EnrollTOTP - s.issueRecoveryCodes: %w", err)
+	}
+
+	return TOTPEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.cfg.MFA.Issuer, a.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (s *mfaService) ConfirmTOTP(ctx context.Context, aid, code string) error {
+	t, err := s.getTOTP(ctx, aid)
+	if err != nil {
+		return fmt.Errorf("mfaService - ConfirmTOTP - s.getTOTP: %w", err)
+	}
+
+	if t.Confirmed {
+		return fmt.Errorf("mfaService - ConfirmTOTP: %w", apperrors.ErrMFAAlreadyEnabled)
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return fmt.Errorf("mfaService - ConfirmTOTP: %w", apperrors.ErrMFAIncorrectCode)
+	}
+
+	if err = s.totpRepo.Confirm(ctx, aid, step); err != nil {
+		return fmt.Errorf("mfaService - ConfirmTOTP - s.totpRepo.Confirm: %w", err)
+	}
+
+	return nil
+}
+
+func (s *mfaService) DisableTOTP(ctx context.Context, aid string) error {
+	if err := s.totpRepo.Delete(ctx, aid); err != nil {
+		return fmt.Errorf("mfaService - DisableTOTP - s.totpRepo.Delete: %w", err)
+	}
+
+	if err := s.deleteUnusableRecoveryCodes(ctx, aid); err != nil {
+		return fmt.Errorf("mfaService - DisableTOTP - s.deleteUnusableRecoveryCodes: %w", err)
+	}
+
+	return nil
+}
+
+func (s *mfaService) EnableEmailOTP(ctx context.Context, aid string) ([]string, error) {
+	if err := s.emailOTPRepo.Enable(ctx, aid); err != nil {
+		return nil, fmt.Errorf("mfaService - EnableEmailOTP - s.emailOTPRepo.Enable: %w", err)
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService - EnableEmailOTP - s.issueRecoveryCodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) DisableEmailOTP(ctx context.Context, aid string) error {
+	if err := s.emailOTPRepo.Disable(ctx, aid); err != nil {
+		return fmt.Errorf("mfaService - DisableEmailOTP - s.emailOTPRepo.Disable: %w", err)
+	}
+
+	if err := s.deleteUnusableRecoveryCodes(ctx, aid); err != nil {
+		return fmt.Errorf("mfaService - DisableEmailOTP - s.deleteUnusableRecoveryCodes: %w", err)
+	}
+
+	return nil
+}
+
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, aid string) ([]string, error) {
+	enabled, err := s.Enabled(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService - RegenerateRecoveryCodes - s.Enabled: %w", err)
+	}
+
+	if !enabled {
+		return nil, fmt.Errorf("mfaService - RegenerateRecoveryCodes: %w", apperrors.ErrMFANotEnabled)
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService - RegenerateRecoveryCodes - s.issueRecoveryCodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) Enabled(ctx context.Context, aid string) (bool, error) {
+	methods, err := s.methods(ctx, aid)
+	if err != nil {
+		return false, fmt.Errorf("mfaService - Enabled - s.methods: %w", err)
+	}
+
+	return len(methods) > 0, nil
+}
+
+func (s *mfaService) CreateChallenge(ctx context.Context, a domain.Account, provider string,
+	d Device) (domain.MFAChallenge, error) {
+
+	methods, err := s.methods(ctx, a.ID)
+	if err != nil {
+		return domain.MFAChallenge{}, fmt.Errorf("mfaService - CreateChallenge - s.methods: %w", err)
+	}
+
+	c, err := domain.NewMFAChallenge(a.ID, provider, a.Archive, methods, d.UserAgent, d.IP, s.cfg.MFA.ChallengeTTL)
+	if err != nil {
+		return domain.MFAChallenge{}, fmt.Errorf("mfaService - CreateChallenge - domain.NewMFAChallenge: %w", err)
+	}
+
+	if err = s.challengeRepo.Create(ctx, c); err != nil {
+		return domain.MFAChallenge{}, fmt.Errorf("mfaService - CreateChallenge - s.challengeRepo.Create: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *mfaService) VerifyTOTP(ctx context.Context, cid, code string, d Device) (domain.Session, error) {
+	c, err := s.getChallenge(ctx, cid, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP - s.getChallenge: %w", err)
+	}
+
+	if !c.Allows(domain.MFAMethodTOTP) {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP: %w", apperrors.ErrMFAMethodNotAllowed)
+	}
+
+	if err = s.challengeRepo.ClaimAttempt(ctx, c.ID, s.cfg.MFA.MaxAttempts); err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP - s.challengeRepo.ClaimAttempt: %w", err)
+	}
+
+	t, err := s.getTOTP(ctx, c.AccountID)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP - s.getTOTP: %w", err)
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if ok && t.Confirmed {
+		err = s.totpRepo.UseStep(ctx, c.AccountID, step)
+		if err != nil && !errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+			return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP - s.totpRepo.UseStep: %w", err)
+		}
+
+		ok = err == nil
+	}
+
+	if !ok {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP: %w", apperrors.ErrMFAIncorrectCode)
+	}
+
+	sess, err := s.completeChallenge(ctx, c, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyTOTP - s.completeChallenge: %w", err)
+	}
+
+	return sess, nil
+}
+
+func (s *mfaService) SendEmailOTP(ctx context.Context, cid string, d Device) error {
+	c, err := s.getChallenge(ctx, cid, d)
+	if err != nil {
+		return fmt.Errorf("mfaService - SendEmailOTP - s.getChallenge: %w", err)
+	}
+
+	if !c.Allows(domain.MFAMethodEmail) {
+		return fmt.Errorf("mfaService - SendEmailOTP: %w", apperrors.ErrMFAMethodNotAllowed)
+	}
+
+	a, err := s.challengeAccount(ctx, c)
+	if err != nil {
+		return fmt.Errorf("mfaService - SendEmailOTP - s.challengeAccount: %w", err)
+	}
+
+	code, hash, err := c.NewEmailCode()
+	if err != nil {
+		return fmt.Errorf("mfaService - SendEmailOTP - c.NewEmailCode: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.cfg.MFA.EmailCodeTTL)
+
+	err = s.challengeRepo.SetEmailCode(ctx, c.ID, hash, expiresAt, now.Add(-s.cfg.MFA.EmailCooldown))
+	if err != nil {
+		return fmt.Errorf("mfaService - SendEmailOTP - s.challengeRepo.SetEmailCode: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"Your login code is %s\n\n"+
+			"The code expires at %s. If you did not try to log in, change your password.",
+		code,
+		expiresAt.Format(time.RFC1123),
+	)
+
+	if err = s.email.Send(ctx, a.Email, "Your login code", body); err != nil {
+		return fmt.Errorf("mfaService - SendEmailOTP - s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+func (s *mfaService) VerifyEmailOTP(ctx context.Context, cid, code string, d Device) (domain.Session, error) {
+	c, err := s.getChallenge(ctx, cid, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyEmailOTP - s.getChallenge: %w", err)
+	}
+
+	if !c.Allows(domain.MFAMethodEmail) {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyEmailOTP: %w", apperrors.ErrMFAMethodNotAllowed)
+	}
+
+	if c.EmailCodeExpired() {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyEmailOTP: %w", apperrors.ErrMFAEmailCodeExpired)
+	}
+
+	if err = s.challengeRepo.ClaimAttempt(ctx, c.ID, s.cfg.MFA.MaxAttempts); err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyEmailOTP - s.challengeRepo.ClaimAttempt: %w", err)
+	}
+
+	if !c.EmailCodeValid(code) {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyEmailOTP: %w", apperrors.ErrMFAIncorrectCode)
+	}
+
+	sess, err := s.completeChallenge(ctx, c, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyEmailOTP - s.completeChallenge: %w", err)
+	}
+
+	return sess, nil
+}
+
+func (s *mfaService) VerifyRecoveryCode(ctx context.Context, cid, code string, d Device) (domain.Session, error) {
+	c, err := s.getChallenge(ctx, cid, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode - s.getChallenge: %w", err)
+	}
+
+	if err = s.challengeRepo.ClaimAttempt(ctx, c.ID, s.cfg.MFA.MaxAttempts); err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode - s.challengeRepo.ClaimAttempt: %w", err)
+	}
+
+	enabled, err := s.Enabled(ctx, c.AccountID)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode - s.Enabled: %w", err)
+	}
+
+	if enabled {
+		err = s.recoveryCodeRepo.Use(ctx, c.AccountID, domain.RecoveryCodeHash(code))
+		if err != nil && !errors.Is(err, apperrors.ErrRecoveryCodeNotFound) {
+			return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode - s.recoveryCodeRepo.Use: %w", err)
+		}
+	}
+
+	if !enabled || err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode: %w", apperrors.ErrRecoveryCodeNotFound)
+	}
+
+	// use of recovery code is recorded before session is created, email is delivered in background
+	if err = s.announceRecoveryCodeUse(ctx, c, d); err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode - s.announceRecoveryCodeUse: %w", err)
+	}
+
+	sess, err := s.completeChallenge(ctx, c, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("mfaService - VerifyRecoveryCode - s.completeChallenge: %w", err)
+	}
+
+	return sess, nil
+}
+
+// private methods ----------------------------------------------------------------------------------------------------
+
+// methods returns second factor methods enabled for account.
+func (s *mfaService) methods(ctx context.Context, aid string) ([]string, error) {
+	var methods []string
+
+	t, err := s.totpRepo.FindByAccountID(ctx, aid)
+	if err != nil && !errors.Is(err, apperrors.ErrMFATOTPNotFound) {
+		return nil, fmt.Errorf("s.totpRepo.FindByAccountID: %w", err)
+	}
+
+	if err == nil && t.Confirmed {
+		methods = append(methods, domain.MFAMethodTOTP)
+	}
+
+	email, err := s.emailOTPRepo.Enabled(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("s.emailOTPRepo.Enabled: %w", err)
+	}
+
+	if email {
+		methods = append(methods, domain.MFAMethodEmail)
+	}
+
+	return methods, nil
+}
+
+// deleteUnusableRecoveryCodes deletes recovery codes of account if it has no second factor left.
+func (s *mfaService) deleteUnusableRecoveryCodes(ctx context.Context, aid string) error {
+	enabled, err := s.Enabled(ctx, aid)
+	if err != nil {
+		return fmt.Errorf("s.Enabled: %w", err)
+	}
+
+	if enabled {
+		return nil
+	}
+
+	if err = s.recoveryCodeRepo.DeleteAll(ctx, aid); err != nil {
+		return fmt.Errorf("s.recoveryCodeRepo.DeleteAll: %w", err)
+	}
+
+	return nil
+}
+
+// issueRecoveryCodes replaces recovery codes of account with new ones.
+func (s *mfaService) issueRecoveryCodes(ctx context.Context, aid string) ([]string, error) {
+	rc, err := domain.NewRecoveryCodes(s.cfg.MFA.RecoveryCodes)
+	if err != nil {
+		return nil, fmt.Errorf("domain.NewRecoveryCodes: %w", err)
+	}
+
+	if err = s.recoveryCodeRepo.Replace(ctx, aid, rc); err != nil {
+		return nil, fmt.Errorf("s.recoveryCodeRepo.Replace: %w", err)
+	}
+
+	if err = s.audit.Record(ctx, aid, domain.AuditRecoveryCodesIssued, nil); err != nil {
+		return nil, fmt.Errorf("s.audit.Record: %w", err)
+	}
+
+	codes := make([]string, len(rc))
+	for i, c := range rc {
+		codes[i] = c.Code
+	}
+
+	return codes, nil
+}
+
+// announceRecoveryCodeUse records use of recovery code to complete challenge in audit
+// and notifies account owner by email.
+func (s *mfaService) announceRecoveryCodeUse(ctx context.Context, c domain.MFAChallenge, d Device) error {
+	left, err := s.recoveryCodeRepo.CountUnused(ctx, c.AccountID)
+	if err != nil {
+		return fmt.Errorf("s.recoveryCodeRepo.CountUnused: %w", err)
+	}
+
+	meta := map[string]string{
+		"ip":        d.IP,
+		"userAgent": d.UserAgent,
+		"codesLeft": strconv.Itoa(left),
+	}
+
+	if err = s.audit.Record(ctx, c.AccountID, domain.AuditRecoveryCodeUsed, meta); err != nil {
+		return fmt.Errorf("s.audit.Record: %w", err)
+	}
+
+	a, err := s.challengeAccount(ctx, c)
+	if err != nil {
+		return fmt.Errorf("s.challengeAccount: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"A recovery code was used to log in to your account from %s (%s).\n\n"+
+			"You have %d recovery codes left. If it wasn't you, reset your password and regenerate recovery codes.",
+		d.IP,
+		d.UserAgent,
+		left,
+	)
+
+	if err = s.email.Send(ctx, a.Email, "Recovery code used", body); err != nil {
+		return fmt.Errorf("s.email.Send: %w", err)
+	}
+
+	return nil
+}
+
+// getTOTP returns totp of account with decrypted secret.
+func (s *mfaService) getTOTP(ctx context.Context, aid string) (domain.TOTP, error) {
+	t, err := s.totpRepo.FindByAccountID(ctx, aid)
+	if err != nil {
+		return domain.TOTP{}, fmt.Errorf("s.totpRepo.FindByAccountID: %w", err)
+	}
+
+	secret, err := s.cipher.Decrypt(t.EncryptedSecret)
+	if err != nil {
+		return domain.TOTP{}, fmt.Errorf("s.cipher.Decrypt: %w", err)
+	}
+
+	t.Secret = string(secret)
+
+	return t, nil
+}
+
+// getChallenge returns not expired challenge created from the same device,
+// challenge is deleted if it has no attempts left. Attempt must be claimed
+// with s.challengeRepo.ClaimAttempt before code is compared since attempts read here may be stale.
+func (s *mfaService) getChallenge(ctx context.Context, cid string, d Device) (domain.MFAChallenge, error) {
+	c, err := s.challengeRepo.FindByID(ctx, cid)
+	if err != nil {
+		return domain.MFAChallenge{}, fmt.Errorf("s.challengeRepo.FindByID: %w", err)
+	}
+
+	if c.Expired() {
+		return domain.MFAChallenge{}, apperrors.ErrMFAChallengeNotFound
+	}
+
+	if c.IP != d.IP || c.UserAgent != d.UserAgent {
+		return domain.MFAChallenge{}, apperrors.ErrSessionDeviceMismatch
+	}
+
+	if c.Attempts >= s.cfg.MFA.MaxAttempts {
+		if err = s.challengeRepo.Delete(ctx, c.ID); err != nil && !errors.Is(err, apperrors.ErrMFAChallengeNotFound) {
+			return domain.MFAChallenge{}, fmt.Errorf("s.challengeRepo.Delete: %w", err)
+		}
+
+		return domain.MFAChallenge{}, apperrors.ErrMFAChallengeNotFound
+	}
+
+	return c, nil
+}
+
+// challengeAccount returns account of the challenge, which is archived if challenge restores it.
+func (s *mfaService) challengeAccount(ctx context.Context, c domain.MFAChallenge) (domain.Account, error) {
+	if c.Restore {
+		a, err := s.account.GetArchivedByID(ctx, c.AccountID)
+		if err != nil {
+			return domain.Account{}, fmt.Errorf("s.account.GetArchivedByID: %w", err)
+		}
+
+		return a, nil
+	}
+
+	a, err := s.account.GetByID(ctx, c.AccountID)
+	if err != nil {
+		return domain.Account{}, fmt.Errorf("s.account.GetByID: %w", err)
+	}
+
+	return a, nil
+}
+
+// completeChallenge deletes challenge, restores archived account and creates session of the challenge provider,
+// deletion guarantees that challenge is completed only once.
+func (s *mfaService) completeChallenge(ctx context.Context, c domain.MFAChallenge, d Device) (domain.Session, error) {
+	if err := s.challengeRepo.Delete(ctx, c.ID); err != nil {
+		return domain.Session{}, fmt.Errorf("s.challengeRepo.Delete: %w", err)
+	}
+
+	if c.Restore {
+		if err := s.account.Reactivate(ctx, c.AccountID); err != nil {
+			return domain.Session{}, fmt.Errorf("s.account.Reactivate: %w", err)
+		}
+	}
+
+	sess, err := s.session.Create(ctx, c.AccountID, c.Provider, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("s.session.Create: %w", err)
+	}
+
+	return sess, nil
+}
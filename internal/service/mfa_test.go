@@ -0,0 +1,499 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/email"
+)
+
+const (
+	testAccountID = "a3c1b1c8-6f4e-4b8e-9b8a-2f1f4c3f0e11"
+	testEmail     = "user@example.com"
+)
+
+var (
+	testDevice     = Device{UserAgent: "test", IP: "127.0.0.1"}
+	emailCodeRegex = regexp.MustCompile(`code is (\d{6})`)
+)
+
+// fakeAccount returns single account which may be archived, other methods must not be called.
+type fakeAccount struct {
+	Account
+	archived bool
+}
+
+func (f *fakeAccount) GetByID(ctx context.Context, aid string) (domain.Account, error) {
+	if aid != testAccountID || f.archived {
+		return domain.Account{}, apperrors.ErrAccountNotFound
+	}
+
+	return domain.Account{ID: aid, Email: testEmail, Verified: true}, nil
+}
+
+func (f *fakeAccount) GetArchivedByID(ctx context.Context, aid string) (domain.Account, error) {
+	if aid != testAccountID || !f.archived {
+		return domain.Account{}, apperrors.ErrAccountNotFound
+	}
+
+	return domain.Account{ID: aid, Email: testEmail, Verified: true, Archive: true}, nil
+}
+
+func (f *fakeAccount) Reactivate(ctx context.Context, aid string) error {
+	if aid != testAccountID || !f.archived {
+		return apperrors.ErrAccountNotFound
+	}
+
+	f.archived = false
+
+	return nil
+}
+
+type fakeSession struct {
+	Session
+}
+
+func (f *fakeSession) Create(ctx context.Context, aid, provider string, d Device) (domain.Session, error) {
+	return domain.Session{ID: "sid", AccountID: aid, Provider: provider, IP: d.IP, UserAgent: d.UserAgent}, nil
+}
+
+type fakeAudit struct {
+	Audit
+}
+
+func (f *fakeAudit) Record(ctx context.Context, aid, action string, meta map[string]string) error {
+	return nil
+}
+
+type fakeTOTPRepo struct {
+	TOTPRepo
+}
+
+func (f *fakeTOTPRepo) FindByAccountID(ctx context.Context, aid string) (domain.TOTP, error) {
+	return domain.TOTP{}, apperrors.ErrMFATOTPNotFound
+}
+
+type fakeEmailOTPRepo struct {
+	enabled map[string]bool
+}
+
+func (f *fakeEmailOTPRepo) Enable(ctx context.Context, aid string) error {
+	f.enabled[aid] = true
+	return nil
+}
+
+func (f *fakeEmailOTPRepo) Enabled(ctx context.Context, aid string) (bool, error) {
+	return f.enabled[aid], nil
+}
+
+func (f *fakeEmailOTPRepo) Disable(ctx context.Context, aid string) error {
+	delete(f.enabled, aid)
+	return nil
+}
+
+// fakeMFAChallengeRepo keeps challenges in memory with the same semantics as mongodb repository.
+type fakeMFAChallengeRepo struct {
+	mu         sync.Mutex
+	challenges map[string]domain.MFAChallenge
+}
+
+func (f *fakeMFAChallengeRepo) Create(ctx context.Context, c domain.MFAChallenge) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.challenges[c.ID] = c
+
+	return nil
+}
+
+func (f *fakeMFAChallengeRepo) FindByID(ctx context.Context, cid string) (domain.MFAChallenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[cid]
+	if !ok {
+		return domain.MFAChallenge{}, apperrors.ErrMFAChallengeNotFound
+	}
+
+	return c, nil
+}
+
+func (f *fakeMFAChallengeRepo) SetEmailCode(ctx context.Context, cid, codeHash string, expiresAt,
+	sentBefore time.Time) error {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[cid]
+	if !ok || c.EmailCodeSentAt.After(sentBefore) {
+		return apperrors.ErrMFAEmailCodeCooldown
+	}
+
+	c.EmailCodeHash = codeHash
+	c.EmailCodeSentAt = time.Now()
+	c.EmailCodeExpiresAt = expiresAt
+	f.challenges[cid] = c
+
+	return nil
+}
+
+func (f *fakeMFAChallengeRepo) ClaimAttempt(ctx context.Context, cid string, max int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[cid]
+	if !ok || c.Attempts >= max {
+		return apperrors.ErrMFAChallengeNotFound
+	}
+
+	c.Attempts++
+	f.challenges[cid] = c
+
+	return nil
+}
+
+func (f *fakeMFAChallengeRepo) Delete(ctx context.Context, cid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.challenges[cid]; !ok {
+		return apperrors.ErrMFAChallengeNotFound
+	}
+
+	delete(f.challenges, cid)
+
+	return nil
+}
+
+// mailbox is implemented by memory email sender.
+type mailbox interface {
+	Last(to string) (email.Message, bool)
+}
+
+type mfaTestEnv struct {
+	service    *mfaService
+	sender     mailbox
+	account    *fakeAccount
+	emailOTP   *fakeEmailOTPRepo
+	challenges *fakeMFAChallengeRepo
+}
+
+func newMFATestEnv(t *testing.T) mfaTestEnv {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.MFA.ChallengeTTL = 5 * time.Minute
+	cfg.MFA.MaxAttempts = 3
+	cfg.MFA.EmailCodeTTL = 5 * time.Minute
+	cfg.MFA.EmailCooldown = time.Minute
+
+	sender := email.NewMemorySender()
+	emailOTP := &fakeEmailOTPRepo{enabled: map[string]bool{testAccountID: true}}
+	challenges := &fakeMFAChallengeRepo{challenges: make(map[string]domain.MFAChallenge)}
+	account := &fakeAccount{}
+
+	s := NewMFAService(
+		cfg,
+		&fakeTOTPRepo{},
+		emailOTP,
+		challenges,
+		nil,
+		account,
+		&fakeSession{},
+		&fakeAudit{},
+		nil,
+		sender,
+	)
+
+	return mfaTestEnv{
+		service:    s,
+		sender:     sender,
+		account:    account,
+		emailOTP:   emailOTP,
+		challenges: challenges,
+	}
+}
+
+func (env mfaTestEnv) createChallenge(t *testing.T) domain.MFAChallenge {
+	t.Helper()
+
+	c, err := env.service.CreateChallenge(context.Background(), domain.Account{ID: testAccountID}, providerEmail, testDevice)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+
+	return c
+}
+
+// lastEmailCode returns code from last email sent to account.
+func (env mfaTestEnv) lastEmailCode(t *testing.T) string {
+	t.Helper()
+
+	m, ok := env.sender.Last(testEmail)
+	if !ok {
+		t.Fatal("email code is not sent")
+	}
+
+	match := emailCodeRegex.FindStringSubmatch(m.Body)
+	if match == nil {
+		t.Fatalf("email code is not found in %q", m.Body)
+	}
+
+	return match[1]
+}
+
+func TestEmailOTP(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestEnv(t)
+
+	c := env.createChallenge(t)
+
+	if !c.Allows(domain.MFAMethodEmail) {
+		t.Fatalf("challenge methods = %v, want %s", c.Methods, domain.MFAMethodEmail)
+	}
+
+	if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+		t.Fatalf("SendEmailOTP: %v", err)
+	}
+
+	code := env.lastEmailCode(t)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	if _, err := env.service.VerifyEmailOTP(ctx, c.ID, wrong, testDevice); !errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+		t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAIncorrectCode)
+	}
+
+	if got := env.challenges.challenges[c.ID].Attempts; got != 1 {
+		t.Fatalf("attempts = %d, want 1", got)
+	}
+
+	sess, err := env.service.VerifyEmailOTP(ctx, c.ID, code, testDevice)
+	if err != nil {
+		t.Fatalf("VerifyEmailOTP: %v", err)
+	}
+
+	if sess.AccountID != testAccountID || sess.Provider != providerEmail {
+		t.Fatalf("session = %+v, want account %s with provider %s", sess, testAccountID, providerEmail)
+	}
+
+	// challenge is completed and code cannot be used again
+	if _, err = env.service.VerifyEmailOTP(ctx, c.ID, code, testDevice); !errors.Is(err, apperrors.ErrMFAChallengeNotFound) {
+		t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAChallengeNotFound)
+	}
+}
+
+func TestEmailOTPCooldown(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestEnv(t)
+
+	c := env.createChallenge(t)
+
+	if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+		t.Fatalf("SendEmailOTP: %v", err)
+	}
+
+	code := env.lastEmailCode(t)
+
+	if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); !errors.Is(err, apperrors.ErrMFAEmailCodeCooldown) {
+		t.Fatalf("SendEmailOTP error = %v, want %v", err, apperrors.ErrMFAEmailCodeCooldown)
+	}
+
+	// code sent before cooldown is still valid
+	if got := env.lastEmailCode(t); got != code {
+		t.Fatalf("code = %s, want %s", got, code)
+	}
+
+	// cooldown is passed
+	ch := env.challenges.challenges[c.ID]
+	ch.EmailCodeSentAt = ch.EmailCodeSentAt.Add(-2 * time.Minute)
+	env.challenges.challenges[c.ID] = ch
+
+	if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+		t.Fatalf("SendEmailOTP: %v", err)
+	}
+
+	newCode := env.lastEmailCode(t)
+
+	if _, err := env.service.VerifyEmailOTP(ctx, c.ID, newCode, testDevice); err != nil {
+		t.Fatalf("VerifyEmailOTP: %v", err)
+	}
+}
+
+func TestEmailOTPRejected(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("code not sent", func(t *testing.T) {
+		env := newMFATestEnv(t)
+		c := env.createChallenge(t)
+
+		if _, err := env.service.VerifyEmailOTP(ctx, c.ID, "123456", testDevice); !errors.Is(err, apperrors.ErrMFAEmailCodeExpired) {
+			t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAEmailCodeExpired)
+		}
+	})
+
+	t.Run("code expired", func(t *testing.T) {
+		env := newMFATestEnv(t)
+		c := env.createChallenge(t)
+
+		if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+			t.Fatalf("SendEmailOTP: %v", err)
+		}
+
+		ch := env.challenges.challenges[c.ID]
+		ch.EmailCodeExpiresAt = time.Now().Add(-time.Second)
+		env.challenges.challenges[c.ID] = ch
+
+		if _, err := env.service.VerifyEmailOTP(ctx, c.ID, env.lastEmailCode(t), testDevice); !errors.Is(err, apperrors.ErrMFAEmailCodeExpired) {
+			t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAEmailCodeExpired)
+		}
+	})
+
+	t.Run("method not enabled", func(t *testing.T) {
+		env := newMFATestEnv(t)
+		c := env.createChallenge(t)
+
+		// challenge keeps methods enabled at the moment of login
+		c.Methods = nil
+		env.challenges.challenges[c.ID] = c
+
+		if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); !errors.Is(err, apperrors.ErrMFAMethodNotAllowed) {
+			t.Fatalf("SendEmailOTP error = %v, want %v", err, apperrors.ErrMFAMethodNotAllowed)
+		}
+
+		if _, ok := env.sender.Last(testEmail); ok {
+			t.Fatal("email code is sent")
+		}
+	})
+
+	t.Run("other device", func(t *testing.T) {
+		env := newMFATestEnv(t)
+		c := env.createChallenge(t)
+
+		other := Device{UserAgent: "other", IP: testDevice.IP}
+
+		if err := env.service.SendEmailOTP(ctx, c.ID, other); !errors.Is(err, apperrors.ErrSessionDeviceMismatch) {
+			t.Fatalf("SendEmailOTP error = %v, want %v", err, apperrors.ErrSessionDeviceMismatch)
+		}
+	})
+
+	t.Run("too many attempts", func(t *testing.T) {
+		env := newMFATestEnv(t)
+		c := env.createChallenge(t)
+
+		if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+			t.Fatalf("SendEmailOTP: %v", err)
+		}
+
+		code := env.lastEmailCode(t)
+
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := env.service.VerifyEmailOTP(ctx, c.ID, wrong, testDevice); !errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+				t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAIncorrectCode)
+			}
+		}
+
+		if _, err := env.service.VerifyEmailOTP(ctx, c.ID, code, testDevice); !errors.Is(err, apperrors.ErrMFAChallengeNotFound) {
+			t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAChallengeNotFound)
+		}
+	})
+}
+
+func TestEmailOTPRestoresArchivedAccount(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestEnv(t)
+	env.account.archived = true
+
+	c, err := env.service.CreateChallenge(ctx, domain.Account{ID: testAccountID, Archive: true}, providerEmail, testDevice)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+
+	if err = env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+		t.Fatalf("SendEmailOTP: %v", err)
+	}
+
+	code := env.lastEmailCode(t)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	// first factor alone must not restore account
+	if _, err = env.service.VerifyEmailOTP(ctx, c.ID, wrong, testDevice); !errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+		t.Fatalf("VerifyEmailOTP error = %v, want %v", err, apperrors.ErrMFAIncorrectCode)
+	}
+
+	if !env.account.archived {
+		t.Fatal("account is restored before second factor is completed")
+	}
+
+	if _, err = env.service.VerifyEmailOTP(ctx, c.ID, code, testDevice); err != nil {
+		t.Fatalf("VerifyEmailOTP: %v", err)
+	}
+
+	if env.account.archived {
+		t.Fatal("account is not restored after second factor is completed")
+	}
+}
+
+func TestEmailOTPParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestEnv(t)
+	c := env.createChallenge(t)
+
+	if err := env.service.SendEmailOTP(ctx, c.ID, testDevice); err != nil {
+		t.Fatalf("SendEmailOTP: %v", err)
+	}
+
+	code := env.lastEmailCode(t)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		guesses int
+	)
+
+	// parallel guesses may read challenge before any attempt is counted
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := env.service.VerifyEmailOTP(ctx, c.ID, wrong, testDevice)
+			if errors.Is(err, apperrors.ErrMFAIncorrectCode) {
+				mu.Lock()
+				guesses++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if guesses > env.service.cfg.MFA.MaxAttempts {
+		t.Fatalf("compared guesses = %d, want at most %d", guesses, env.service.cfg.MFA.MaxAttempts)
+	}
+}
@@ -4,11 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
-	"github.com/google/go-github/github"
 	"golang.org/x/oauth2"
 
 	"github.com/ysomad/go-auth-service/config"
@@ -17,87 +16,273 @@ import (
 	"github.com/ysomad/go-auth-service/pkg/utils"
 )
 
-// Provider constants to track how user is logged in
+// Provider constants to track how user is logged in, social providers are tracked by name from config
 const (
-	providerEmail    = "email"
-	providerUsername = "username"
-	providerGitHub   = "github"
-	providerGoogle   = "google"
+	providerEmail     = "email"
+	providerUsername  = "username"
+	providerWebAuthn  = "webauthn"
+	providerMagicLink = "magic_link"
 )
 
+// usernameMaxLen is max length of username generated for accounts created via social login,
+// 4 random digits are appended to it.
+const usernameMaxLen = 12
+
 type socialAuthService struct {
 	cfg            *config.Config
 	accountService Account
 	sessionService Session
+	mfaService     MFA
+	audit          Audit
+	providers      map[string]SocialProvider
+	stateRepo      OAuthStateRepo
+	identityRepo   IdentityRepo
+	credentialRepo WebAuthnCredentialRepo
 }
 
-func NewSocialAuthService(cfg *config.Config, a Account, s Session) *socialAuthService {
+func NewSocialAuthService(cfg *config.Config, a Account, s Session, m MFA, au Audit, p map[string]SocialProvider,
+	sr OAuthStateRepo, ir IdentityRepo, cr WebAuthnCredentialRepo) *socialAuthService {
+
 	return &socialAuthService{
 		cfg:            cfg,
 		accountService: a,
 		sessionService: s,
+		mfaService:     m,
+		audit:          au,
+		providers:      p,
+		stateRepo:      sr,
+		identityRepo:   ir,
+		credentialRepo: cr,
+	}
+}
+
+func (s *socialAuthService) AuthorizationURL(ctx context.Context, provider, browserToken string) (*url.URL, error) {
+	u, err := s.authorizationURL(ctx, "", strings.ToLower(provider), browserToken)
+	if err != nil {
+		return nil, fmt.Errorf("socialAuthService - AuthorizationURL - s.authorizationURL: %w", err)
 	}
+
+	return u, nil
 }
 
-func (s *socialAuthService) AuthorizationURL(ctx context.Context, provider string) (*url.URL, error) {
+func (s *socialAuthService) Login(ctx context.Context, provider, code, state, browserToken string,
+	d Device) (LoginResult, error) {
+
 	provider = strings.ToLower(provider)
 
-	scope, err := utils.UniqueString(32)
+	id, err := s.identity(ctx, "", provider, code, state, browserToken)
 	if err != nil {
-		return nil, fmt.Errorf("socialAuthService - AuthorizationURL - util.UniqueString: %w", err)
+		return LoginResult{}, fmt.Errorf("socialAuthService - Login - s.identity: %w", err)
 	}
 
-	u, err := url.Parse(s.cfg.Endpoints()[provider].AuthURL)
+	var a domain.Account
+
+	i, err := s.identityRepo.FindByProviderUserID(ctx, provider, id.Subject)
+	if err == nil {
+		a, err = s.accountService.GetByID(ctx, i.AccountID)
+		if err != nil {
+			return LoginResult{}, fmt.Errorf("socialAuthService - Login - s.accountService.GetByID: %w", err)
+		}
+	} else {
+		if !errors.Is(err, apperrors.ErrIdentityNotFound) {
+			return LoginResult{}, fmt.Errorf("socialAuthService - Login - s.identityRepo.FindByProviderUserID: %w", err)
+		}
+
+		a, err = s.linkOrSignUp(ctx, provider, id)
+		if err != nil {
+			return LoginResult{}, fmt.Errorf("socialAuthService - Login - s.linkOrSignUp: %w", err)
+		}
+	}
+
+	if s.cfg.Verification.LoginRequired && !a.Verified {
+		return LoginResult{}, fmt.Errorf("socialAuthService - Login: %w", apperrors.ErrAccountNotVerified)
+	}
+
+	res, err := createSession(ctx, s.mfaService, s.accountService, s.sessionService, a, provider, d)
 	if err != nil {
-		return nil, fmt.Errorf("socialAuthService - AuthorizationURL - url.Parse: %w", err)
+		return LoginResult{}, fmt.Errorf("socialAuthService - Login - createSession: %w", err)
 	}
 
-	q := u.Query()
-	q.Set("client_id", s.cfg.ClientIDs()[provider])
-	q.Set("scope", s.cfg.Scopes()[provider])
-	q.Set("state", scope)
-	u.RawQuery = q.Encode()
+	return res, nil
+}
+
+func (s *socialAuthService) LinkURL(ctx context.Context, aid, provider, browserToken string) (*url.URL, error) {
+	u, err := s.authorizationURL(ctx, aid, strings.ToLower(provider), browserToken)
+	if err != nil {
+		return nil, fmt.Errorf("socialAuthService - LinkURL - s.authorizationURL: %w", err)
+	}
 
 	return u, nil
 }
 
-func (s *socialAuthService) GitHubLogin(ctx context.Context, code string, d Device) (domain.Session, error) {
-	t, err := s.exchangeCode(ctx, providerGitHub, code)
+func (s *socialAuthService) Link(ctx context.Context, aid, provider, code, state,
+	browserToken string) (domain.Identity, error) {
+
+	provider = strings.ToLower(provider)
+
+	id, err := s.identity(ctx, aid, provider, code, state, browserToken)
+	if err != nil {
+		return domain.Identity{}, fmt.Errorf("socialAuthService - Link - s.identity: %w", err)
+	}
+
+	i, err := s.link(ctx, aid, provider, id)
+	if err != nil {
+		return domain.Identity{}, fmt.Errorf("socialAuthService - Link - s.link: %w", err)
+	}
+
+	return i, nil
+}
+
+func (s *socialAuthService) Unlink(ctx context.Context, aid, provider string) error {
+	provider = strings.ToLower(provider)
+
+	a, err := s.accountService.GetByID(ctx, aid)
 	if err != nil {
-		return domain.Session{}, fmt.Errorf("socialAuthService  - GitHubLogin - s.exchangeCode: %w", err)
+		return fmt.Errorf("socialAuthService - Unlink - s.accountService.GetByID: %w", err)
 	}
 
-	u, err := s.getGitHubUser(ctx, t)
+	identities, err := s.identityRepo.FindAll(ctx, aid)
 	if err != nil {
-		return domain.Session{}, fmt.Errorf("socialAuthService - GitHubLogin - s.getGitHubUser: %w", err)
+		return fmt.Errorf("socialAuthService - Unlink - s.identityRepo.FindAll: %w", err)
 	}
 
-	sess, err := s.loginOrSignUp(ctx, *u.Email, *u.Login, providerGitHub, d)
+	credentials, err := s.credentialRepo.FindAll(ctx, aid)
 	if err != nil {
-		return domain.Session{}, fmt.Errorf("socialAuthService - GitHubLogin - s.loginOrSignUp: %w", err)
+		return fmt.Errorf("socialAuthService - Unlink - s.credentialRepo.FindAll: %w", err)
 	}
 
-	return sess, nil
+	linked := false
+	for _, i := range identities {
+		if i.Provider == provider {
+			linked = true
+			break
+		}
+	}
+
+	if !linked {
+		return fmt.Errorf("socialAuthService - Unlink: %w", apperrors.ErrIdentityNotFound)
+	}
+
+	// generated password is unknown to user until it is reset
+	methods := len(identities) + len(credentials)
+	if !a.PasswordGenerated {
+		methods++
+	}
+
+	if methods <= 1 {
+		return fmt.Errorf("socialAuthService - Unlink: %w", apperrors.ErrIdentityLastLoginMethod)
+	}
+
+	if err = s.identityRepo.Delete(ctx, aid, provider); err != nil {
+		return fmt.Errorf("socialAuthService - Unlink - s.identityRepo.Delete: %w", err)
+	}
+
+	if err = s.audit.Record(ctx, aid, domain.AuditIdentityUnlinked, map[string]string{"provider": provider}); err != nil {
+		return fmt.Errorf("socialAuthService - Unlink - s.audit.Record: %w", err)
+	}
+
+	return nil
 }
 
-func (s *socialAuthService) GoogleLogin(ctx context.Context, code string, d Device) (domain.Session, error) {
-	panic("implement")
-	return domain.Session{}, nil
+func (s *socialAuthService) Identities(ctx context.Context, aid string) ([]domain.Identity, error) {
+	identities, err := s.identityRepo.FindAll(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("socialAuthService - Identities - s.identityRepo.FindAll: %w", err)
+	}
+
+	return identities, nil
 }
 
 // private methods ----------------------------------------------------------------------------------------------------
 
-// exchangeCode sends OAuth2 authorization code to data provider authorization server in order to
-// get REST API access token which is used to use private provider api.
-func (s *socialAuthService) exchangeCode(ctx context.Context, provider, code string) (*oauth2.Token, error) {
-	o := oauth2.Config{
-		ClientID:     s.cfg.ClientIDs()[provider],
-		ClientSecret: s.cfg.ClientSecrets()[provider],
-		Endpoint:     s.cfg.Endpoints()[provider],
-		Scopes:       strings.Split(s.cfg.Scopes()[provider], ","),
+// provider returns social provider from registry by its lowercased name.
+func (s *socialAuthService) provider(name string) (SocialProvider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, apperrors.ErrAuthProviderNotSupported
 	}
 
-	t, err := o.Exchange(ctx, code)
+	return p, nil
+}
+
+// authorizationURL stores state issued for account, empty for login, and returns authorization url with it.
+func (s *socialAuthService) authorizationURL(ctx context.Context, aid, provider, browserToken string) (*url.URL, error) {
+	p, err := s.provider(provider)
+	if err != nil {
+		return nil, fmt.Errorf("s.provider: %w", err)
+	}
+
+	o, err := p.OAuth2Config(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("p.OAuth2Config: %w", err)
+	}
+
+	state, err := domain.NewOAuthState(aid, provider, browserToken, s.cfg.SocialAuth.StateTTL)
+	if err != nil {
+		return nil, fmt.Errorf("domain.NewOAuthState: %w", err)
+	}
+
+	if err = s.stateRepo.Create(ctx, state); err != nil {
+		return nil, fmt.Errorf("s.stateRepo.Create: %w", err)
+	}
+
+	u, err := url.Parse(o.AuthCodeURL(
+		state.State,
+		oauth2.SetAuthURLParam("code_challenge", state.CodeChallenge()),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse: %w", err)
+	}
+
+	return u, nil
+}
+
+// identity consumes state issued for account, empty for login, and returns user identity
+// received from provider with authorization code.
+func (s *socialAuthService) identity(ctx context.Context, aid, provider, code, state,
+	browserToken string) (SocialIdentity, error) {
+
+	p, err := s.provider(provider)
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("s.provider: %w", err)
+	}
+
+	st, err := s.stateRepo.Consume(ctx, utils.SHA256(state), aid, provider, utils.SHA256(browserToken))
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("s.stateRepo.Consume: %w", err)
+	}
+
+	if st.Expired() {
+		return SocialIdentity{}, apperrors.ErrOAuthStateExpired
+	}
+
+	t, err := s.exchangeCode(ctx, p, code, st.CodeVerifier)
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("s.exchangeCode: %w", err)
+	}
+
+	id, err := p.Identity(ctx, t)
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("p.Identity: %w", err)
+	}
+
+	if id.Subject == "" {
+		return SocialIdentity{}, apperrors.ErrIdentitySubjectNotFound
+	}
+
+	return id, nil
+}
+
+// exchangeCode sends OAuth2 authorization code with PKCE code verifier to data provider authorization server
+// in order to get REST API access token which is used to use private provider api.
+func (s *socialAuthService) exchangeCode(ctx context.Context, p SocialProvider, code, codeVerifier string) (*oauth2.Token, error) {
+	o, err := p.OAuth2Config(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("p.OAuth2Config: %w", err)
+	}
+
+	t, err := o.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
 	if err != nil {
 		return nil, fmt.Errorf("o.Exchange: %w", err)
 	}
@@ -105,52 +290,97 @@ func (s *socialAuthService) exchangeCode(ctx context.Context, provider, code str
 	return t, nil
 }
 
-// getGitHubUser returns github user using access token received from exchangeCode method.
-func (s *socialAuthService) getGitHubUser(ctx context.Context, t *oauth2.Token) (*github.User, error) {
-	ts := oauth2.StaticTokenSource(t)
-	tc := oauth2.NewClient(ctx, ts)
-	gh := github.NewClient(tc)
+// usernameFromEmail returns username made of alphanumeric characters of email local part
+// and random digits since providers without usernames share no unique name.
+func usernameFromEmail(email string) (string, error) {
+	local := email
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		local = email[:i]
+	}
+
+	var b strings.Builder
 
-	u, r, err := gh.Users.Get(ctx, "")
-	if err != nil {
-		return nil, fmt.Errorf("gh.Users.Get: %w", err)
+	for _, r := range local {
+		if b.Len() == usernameMaxLen {
+			break
+		}
+
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
 	}
 
-	if r.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("gh.Users.Get: %w", apperrors.ErrAuthGitHubUserNotReceived)
+	digits, err := utils.UniqueDigits(4)
+	if err != nil {
+		return "", fmt.Errorf("utils.UniqueDigits: %w", err)
 	}
 
-	return u, nil
+	return b.String() + digits, nil
 }
 
-// loginOrSignUp logs in user with received data from OAuth2 data provider if account exist or creates
-// new account with random password and logs it in.
-func (s *socialAuthService) loginOrSignUp(
-	ctx context.Context, email, username, provider string, d Device) (domain.Session, error) {
+// linkOrSignUp links user identity to account with the same email or to new account with random password
+// and returns the account, email must be verified by provider since email of the user is not verified by the service.
+// Identity is not linked to account which email is not verified since it may be registered by someone
+// who does not own the email, the user must verify the account or link identity after login.
+func (s *socialAuthService) linkOrSignUp(ctx context.Context, provider string, id SocialIdentity) (domain.Account, error) {
+	if id.Email == "" {
+		return domain.Account{}, apperrors.ErrAuthEmailNotReceived
+	}
 
-	var aid string
+	if !id.EmailVerified {
+		return domain.Account{}, apperrors.ErrAuthEmailNotVerified
+	}
 
-	a, err := s.accountService.GetByEmail(ctx, email)
-	if err == nil {
-		aid = a.ID
-	} else {
+	a, err := s.accountService.GetByEmail(ctx, id.Email)
+	if err != nil {
 		if !errors.Is(err, apperrors.ErrAccountNotFound) {
-			return domain.Session{}, fmt.Errorf("s.accountService.GetByEmail: %w", err)
+			return domain.Account{}, fmt.Errorf("s.accountService.GetByEmail: %w", err)
+		}
+
+		username := id.Username
+		if username == "" {
+			if username, err = usernameFromEmail(id.Email); err != nil {
+				return domain.Account{}, fmt.Errorf("usernameFromEmail: %w", err)
+			}
 		}
 
-		a = domain.Account{Email: email, Username: username, Verified: true}
-		a.RandomPassword()
+		a = domain.Account{Email: id.Email, Username: username, Verified: true}
 
-		aid, err = s.accountService.Create(ctx, a)
-		if err != nil {
-			return domain.Session{}, fmt.Errorf("s.accountService.Create: %w", err)
+		if err = a.RandomPassword(); err != nil {
+			return domain.Account{}, fmt.Errorf("a.RandomPassword: %w", err)
 		}
+
+		if a.ID, err = s.accountService.Create(ctx, a); err != nil {
+			return domain.Account{}, fmt.Errorf("s.accountService.Create: %w", err)
+		}
+	} else if !a.Verified {
+		return domain.Account{}, apperrors.ErrAuthAccountNotVerified
 	}
 
-	sess, err := s.sessionService.Create(ctx, aid, provider, d)
-	if err != nil {
-		return domain.Session{}, fmt.Errorf("s.sessionService.Create: %w", err)
+	if _, err = s.link(ctx, a.ID, provider, id); err != nil {
+		return domain.Account{}, fmt.Errorf("s.link: %w", err)
+	}
+
+	return a, nil
+}
+
+// link creates identity of the user linked to account.
+func (s *socialAuthService) link(ctx context.Context, aid, provider string, id SocialIdentity) (domain.Identity, error) {
+	i := domain.Identity{
+		Provider:       provider,
+		ProviderUserID: id.Subject,
+		AccountID:      aid,
+		Email:          id.Email,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := s.identityRepo.Create(ctx, i); err != nil {
+		return domain.Identity{}, fmt.Errorf("s.identityRepo.Create: %w", err)
+	}
+
+	if err := s.audit.Record(ctx, aid, domain.AuditIdentityLinked, map[string]string{"provider": provider}); err != nil {
+		return domain.Identity{}, fmt.Errorf("s.audit.Record: %w", err)
 	}
 
-	return sess, nil
+	return i, nil
 }
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+	oauth2github "golang.org/x/oauth2/github"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/oidc"
+)
+
+// Social provider types
+const (
+	socialProviderOIDC   = "oidc"
+	socialProviderGitHub = "github"
+)
+
+// gitHubScopeEmail is required to list emails of GitHub user which hides email from profile.
+const gitHubScopeEmail = "user:email"
+
+// SocialIdentity represents data transfer object with user data received from social provider.
+type SocialIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// NewSocialProviders creates registry of social providers by name from config.
+func NewSocialProviders(cfg *config.Config) (map[string]SocialProvider, error) {
+	providers := make(map[string]SocialProvider, len(cfg.SocialAuth.Providers))
+
+	for name, p := range cfg.SocialAuth.Providers {
+		secret, ok := cfg.SocialAuth.ClientSecrets[name]
+		if !ok {
+			return nil, fmt.Errorf("client secret of %q: %w", name, apperrors.ErrAuthProviderNotSupported)
+		}
+
+		switch p.Type {
+		case socialProviderOIDC:
+			providers[name] = &oidcProvider{oidc.NewProvider(oidc.Config{
+				Issuer:        p.Issuer,
+				IssuerAliases: p.IssuerAliases,
+				ClientID:      p.ClientID,
+				ClientSecret:  secret,
+				RedirectURL:   p.RedirectURL,
+				Scopes:        p.Scopes,
+				Claims: oidc.ClaimMapping{
+					Email:         p.Claims.Email,
+					EmailVerified: p.Claims.EmailVerified,
+					Username:      p.Claims.Username,
+				},
+			})}
+		case socialProviderGitHub:
+			// copy scopes so appending does not write into backing array of config
+			scopes := append([]string(nil), p.Scopes...)
+
+			hasEmail := false
+			for _, s := range scopes {
+				if s == gitHubScopeEmail {
+					hasEmail = true
+					break
+				}
+			}
+
+			if !hasEmail {
+				scopes = append(scopes, gitHubScopeEmail)
+			}
+
+			providers[name] = &gitHubProvider{&oauth2.Config{
+				ClientID:     p.ClientID,
+				ClientSecret: secret,
+				RedirectURL:  p.RedirectURL,
+				Scopes:       scopes,
+				Endpoint:     oauth2github.Endpoint,
+			}}
+		default:
+			return nil, fmt.Errorf("type %q of %q: %w", p.Type, name, apperrors.ErrAuthProviderNotSupported)
+		}
+	}
+
+	return providers, nil
+}
+
+// oidcProvider gets user from ID token issued by OpenID Connect provider.
+type oidcProvider struct {
+	*oidc.Provider
+}
+
+func (p *oidcProvider) Identity(ctx context.Context, t *oauth2.Token) (SocialIdentity, error) {
+	raw, ok := t.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return SocialIdentity{}, fmt.Errorf("t.Extra: %w", apperrors.ErrAuthIDTokenNotReceived)
+	}
+
+	c, err := p.Verify(ctx, raw)
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("p.Verify: %s: %w", err, apperrors.ErrAuthIDTokenInvalid)
+	}
+
+	return SocialIdentity{
+		Subject:       c.Subject,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+		Username:      c.Username,
+	}, nil
+}
+
+// gitHubProvider gets user from GitHub API since GitHub does not support OpenID Connect.
+type gitHubProvider struct {
+	cfg *oauth2.Config
+}
+
+func (p *gitHubProvider) OAuth2Config(ctx context.Context) (*oauth2.Config, error) {
+	o := *p.cfg
+	return &o, nil
+}
+
+// Identity returns github user using access token received from exchangeCode method,
+// subject is numeric id of the user since login and emails can be changed.
+// Email is primary verified email from emails api since profile email is empty if user hides it.
+func (p *gitHubProvider) Identity(ctx context.Context, t *oauth2.Token) (SocialIdentity, error) {
+	gh := github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(t)))
+
+	u, r, err := gh.Users.Get(ctx, "")
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("gh.Users.Get: %w", err)
+	}
+
+	if r.StatusCode != http.StatusOK || u.GetID() == 0 {
+		return SocialIdentity{}, fmt.Errorf("gh.Users.Get: %w", apperrors.ErrAuthGitHubUserNotReceived)
+	}
+
+	email, err := p.primaryEmail(ctx, gh)
+	if err != nil {
+		return SocialIdentity{}, fmt.Errorf("p.primaryEmail: %w", err)
+	}
+
+	return SocialIdentity{
+		Subject:       strconv.FormatInt(u.GetID(), 10),
+		Email:         email,
+		EmailVerified: email != "",
+		Username:      u.GetLogin(),
+	}, nil
+}
+
+// primaryEmail returns primary email of GitHub user if it is verified, empty string otherwise.
+func (p *gitHubProvider) primaryEmail(ctx context.Context, gh *github.Client) (string, error) {
+	opt := &github.ListOptions{PerPage: 100}
+
+	for {
+		emails, r, err := gh.Users.ListEmails(ctx, opt)
+		if err != nil {
+			return "", fmt.Errorf("gh.Users.ListEmails: %w", err)
+		}
+
+		for _, e := range emails {
+			if e.GetPrimary() {
+				if !e.GetVerified() {
+					return "", nil
+				}
+
+				return e.GetEmail(), nil
+			}
+		}
+
+		if r.NextPage == 0 {
+			return "", nil
+		}
+
+		opt.Page = r.NextPage
+	}
+}
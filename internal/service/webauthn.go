@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysomad/go-auth-service/config"
+	"github.com/ysomad/go-auth-service/internal/domain"
+	"github.com/ysomad/go-auth-service/pkg/apperrors"
+	"github.com/ysomad/go-auth-service/pkg/webauthn"
+)
+
+type webAuthnService struct {
+	cfg            *config.Config
+	credentialRepo WebAuthnCredentialRepo
+	ceremonyRepo   WebAuthnCeremonyRepo
+	account        Account
+	session        Session
+	audit          Audit
+	rp             *webauthn.RelyingParty
+}
+
+func NewWebAuthnService(cfg *config.Config, cr WebAuthnCredentialRepo, wr WebAuthnCeremonyRepo, a Account,
+	s Session, au Audit, rp *webauthn.RelyingParty) *webAuthnService {
+
+	return &webAuthnService{
+		cfg:            cfg,
+		credentialRepo: cr,
+		ceremonyRepo:   wr,
+		account:        a,
+		session:        s,
+		audit:          au,
+		rp:             rp,
+	}
+}
+
+// WebAuthnRegistration represents data transfer object with data required
+// to create credential options for authenticator.
+type WebAuthnRegistration struct {
+	Ceremony    domain.WebAuthnCeremony
+	Account     domain.Account
+	Credentials []domain.WebAuthnCredential
+}
+
+// WebAuthnAttestation represents data transfer object with
+// authenticator response of navigator.credentials.create.
+type WebAuthnAttestation struct {
+	Name              string
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// WebAuthnAssertion represents data transfer object with
+// authenticator response of navigator.credentials.get.
+type WebAuthnAssertion struct {
+	CredentialID      string
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+}
+
+func (s *webAuthnService) BeginRegistration(ctx context.Context, aid string, d Device) (WebAuthnRegistration, error) {
+	a, err := s.account.GetByID(ctx, aid)
+	if err != nil {
+		return WebAuthnRegistration{}, fmt.Errorf("webAuthnService - BeginRegistration - s.account.GetByID: %w", err)
+	}
+
+	credentials, err := s.credentialRepo.FindAll(ctx, aid)
+	if err != nil {
+		return WebAuthnRegistration{}, fmt.Errorf("webAuthnService - BeginRegistration - s.credentialRepo.FindAll: %w", err)
+	}
+
+	c, err := s.beginCeremony(ctx, aid, domain.WebAuthnRegistration, d)
+	if err != nil {
+		return WebAuthnRegistration{}, fmt.Errorf("webAuthnService - BeginRegistration - s.beginCeremony: %w", err)
+	}
+
+	return WebAuthnRegistration{
+		Ceremony:    c,
+		Account:     a,
+		Credentials: credentials,
+	}, nil
+}
+
+func (s *webAuthnService) FinishRegistration(ctx context.Context, aid, cid string, att WebAuthnAttestation,
+	d Device) (domain.WebAuthnCredential, error) {
+
+	c, err := s.consumeCeremony(ctx, cid, domain.WebAuthnRegistration, d)
+	if err != nil {
+		return domain.WebAuthnCredential{}, fmt.Errorf("webAuthnService - FinishRegistration - s.consumeCeremony: %w", err)
+	}
+
+	if c.AccountID != aid {
+		return domain.WebAuthnCredential{}, fmt.Errorf("webAuthnService - FinishRegistration: %w", apperrors.ErrWebAuthnCeremonyNotFound)
+	}
+
+	wc, err := s.rp.VerifyRegistration(c.Challenge, att.ClientDataJSON, att.AttestationObject)
+	if err != nil {
+		return domain.WebAuthnCredential{}, fmt.Errorf("webAuthnService - FinishRegistration - s.rp.VerifyRegistration: %s: %w",
+			err.Error(), apperrors.ErrWebAuthnVerificationFailed)
+	}
+
+	cred := domain.WebAuthnCredential{
+		ID:        webauthn.Encoding.EncodeToString(wc.ID),
+		AccountID: aid,
+		Name:      att.Name,
+		PublicKey: wc.PublicKey,
+		SignCount: wc.SignCount,
+		CreatedAt: time.Now(),
+	}
+
+	if err = s.credentialRepo.Create(ctx, cred); err != nil {
+		return domain.WebAuthnCredential{}, fmt.Errorf("webAuthnService - FinishRegistration - s.credentialRepo.Create: %w", err)
+	}
+
+	err = s.audit.Record(ctx, aid, domain.AuditWebAuthnCredentialAdded, map[string]string{
+		"credentialId": cred.ID,
+		"name":         cred.Name,
+	})
+	if err != nil {
+		return domain.WebAuthnCredential{}, fmt.Errorf("webAuthnService - FinishRegistration - s.audit.Record: %w", err)
+	}
+
+	return cred, nil
+}
+
+func (s *webAuthnService) GetAllCredentials(ctx context.Context, aid string) ([]domain.WebAuthnCredential, error) {
+	credentials, err := s.credentialRepo.FindAll(ctx, aid)
+	if err != nil {
+		return nil, fmt.Errorf("webAuthnService - GetAllCredentials - s.credentialRepo.FindAll: %w", err)
+	}
+
+	return credentials, nil
+}
+
+func (s *webAuthnService) DeleteCredential(ctx context.Context, aid, id string) error {
+	if err := s.credentialRepo.Delete(ctx, aid, id); err != nil {
+		return fmt.Errorf("webAuthnService - DeleteCredential - s.credentialRepo.Delete: %w", err)
+	}
+
+	if err := s.audit.Record(ctx, aid, domain.AuditWebAuthnCredentialRemoved, map[string]string{"credentialId": id}); err != nil {
+		return fmt.Errorf("webAuthnService - DeleteCredential - s.audit.Record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *webAuthnService) BeginLogin(ctx context.Context, d Device) (domain.WebAuthnCeremony, error) {
+	c, err := s.beginCeremony(ctx, "", domain.WebAuthnLogin, d)
+	if err != nil {
+		return domain.WebAuthnCeremony{}, fmt.Errorf("webAuthnService - BeginLogin - s.beginCeremony: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *webAuthnService) FinishLogin(ctx context.Context, cid string, as WebAuthnAssertion, d Device) (domain.Session, error) {
+	c, err := s.consumeCeremony(ctx, cid, domain.WebAuthnLogin, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("webAuthnService - FinishLogin - s.consumeCeremony: %w", err)
+	}
+
+	cred, err := s.credentialRepo.FindByID(ctx, as.CredentialID)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("webAuthnService - FinishLogin - s.credentialRepo.FindByID: %w", err)
+	}
+
+	signCount, err := s.rp.VerifyAssertion(
+		c.Challenge,
+		cred.PublicKey,
+		cred.SignCount,
+		as.ClientDataJSON,
+		as.AuthenticatorData,
+		as.Signature,
+	)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("webAuthnService - FinishLogin - s.rp.VerifyAssertion: %s: %w",
+			err.Error(), apperrors.ErrWebAuthnVerificationFailed)
+	}
+
+	if err = s.credentialRepo.UpdateSignCount(ctx, cred.ID, cred.SignCount, signCount); err != nil {
+		return domain.Session{}, fmt.Errorf("webAuthnService - FinishLogin - s.credentialRepo.UpdateSignCount: %w", err)
+	}
+
+	// Credentials of archived accounts are kept until erasure but must not be used for login
+	if _, err = s.account.GetByID(ctx, cred.AccountID); err != nil {
+		return domain.Session{}, fmt.Errorf("webAuthnService - FinishLogin - s.account.GetByID: %w", err)
+	}
+
+	sess, err := s.session.Create(ctx, cred.AccountID, providerWebAuthn, d)
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("webAuthnService - FinishLogin - s.session.Create: %w", err)
+	}
+
+	return sess, nil
+}
+
+// private methods ---
+
+func (s *webAuthnService) beginCeremony(ctx context.Context, aid, ceremonyType string, d Device) (domain.WebAuthnCeremony, error) {
+	c, err := domain.NewWebAuthnCeremony(aid, ceremonyType, d.UserAgent, d.IP, s.cfg.WebAuthn.CeremonyTTL)
+	if err != nil {
+		return domain.WebAuthnCeremony{}, fmt.Errorf("domain.NewWebAuthnCeremony: %w", err)
+	}
+
+	if err = s.ceremonyRepo.Create(ctx, c); err != nil {
+		return domain.WebAuthnCeremony{}, fmt.Errorf("s.ceremonyRepo.Create: %w", err)
+	}
+
+	return c, nil
+}
+
+// consumeCeremony returns ceremony of given type started from the same device,
+// ceremony is deleted so its challenge cannot be replayed.
+func (s *webAuthnService) consumeCeremony(ctx context.Context, cid, ceremonyType string, d Device) (domain.WebAuthnCeremony, error) {
+	c, err := s.ceremonyRepo.Consume(ctx, cid)
+	if err != nil {
+		return domain.WebAuthnCeremony{}, fmt.Errorf("s.ceremonyRepo.Consume: %w", err)
+	}
+
+	if c.Expired() || c.Type != ceremonyType {
+		return domain.WebAuthnCeremony{}, apperrors.ErrWebAuthnCeremonyNotFound
+	}
+
+	if c.IP != d.IP || c.UserAgent != d.UserAgent {
+		return domain.WebAuthnCeremony{}, apperrors.ErrSessionDeviceMismatch
+	}
+
+	return c, nil
+}
@@ -0,0 +1,6 @@
+create table if not exists account_verifications(
+    account_id uuid primary key references accounts (id) on delete cascade,
+    code varchar(64) unique not null,
+    expires_at timestamp with time zone not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
@@ -0,0 +1,8 @@
+create table if not exists password_resets(
+    token varchar(64) primary key,
+    account_id uuid not null references accounts (id) on delete cascade,
+    expires_at timestamp with time zone not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+create index if not exists password_resets_account_id_idx on password_resets (account_id);
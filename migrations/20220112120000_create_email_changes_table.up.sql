@@ -0,0 +1,11 @@
+create table if not exists email_changes(
+    account_id uuid primary key references accounts (id) on delete cascade,
+    old_email varchar(255) not null,
+    new_email varchar(255) not null,
+    token varchar(64) unique not null,
+    revert_token varchar(64) unique not null,
+    is_confirmed boolean default false not null,
+    expires_at timestamp with time zone not null,
+    revert_expires_at timestamp with time zone not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
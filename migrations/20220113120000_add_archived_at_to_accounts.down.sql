@@ -0,0 +1 @@
+alter table accounts drop column if exists archived_at;
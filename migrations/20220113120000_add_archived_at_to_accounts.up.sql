@@ -0,0 +1,2 @@
+alter table accounts add column if not exists archived_at timestamp with time zone;
+update accounts set archived_at = updated_at where is_archive = true;
@@ -0,0 +1,10 @@
+-- audit records are not bound to accounts by foreign key to outlive account erasure
+create table if not exists audit_records(
+    id uuid primary key default gen_random_uuid(),
+    account_id uuid not null,
+    action varchar(64) not null,
+    metadata jsonb default '{}'::jsonb not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+create index if not exists audit_records_account_id_idx on audit_records (account_id);
@@ -0,0 +1 @@
+drop index if exists accounts_archived_at_idx;
@@ -0,0 +1 @@
+create index if not exists accounts_archived_at_idx on accounts (archived_at) where is_archive = true;
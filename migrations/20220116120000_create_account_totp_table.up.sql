@@ -0,0 +1,7 @@
+create table if not exists account_totp(
+    account_id uuid primary key references accounts (id) on delete cascade,
+    secret bytea not null,
+    is_confirmed boolean default false not null,
+    last_used_step bigint default 0 not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
@@ -0,0 +1 @@
+drop table if exists recovery_codes;
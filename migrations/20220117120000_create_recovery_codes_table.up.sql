@@ -0,0 +1,8 @@
+create table if not exists recovery_codes(
+    id uuid primary key default gen_random_uuid(),
+    account_id uuid not null references accounts (id) on delete cascade,
+    code varchar(64) not null,
+    used_at timestamp with time zone,
+    created_at timestamp with time zone default current_timestamp not null,
+    unique (account_id, code)
+);
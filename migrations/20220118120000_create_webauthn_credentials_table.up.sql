@@ -0,0 +1,11 @@
+create table if not exists webauthn_credentials(
+    id text primary key,
+    account_id uuid not null references accounts (id) on delete cascade,
+    name varchar(64) not null,
+    public_key bytea not null,
+    sign_count bigint default 0 not null,
+    created_at timestamp with time zone default current_timestamp not null,
+    last_used_at timestamp with time zone
+);
+
+create index if not exists webauthn_credentials_account_id_idx on webauthn_credentials (account_id);
@@ -0,0 +1,11 @@
+create table if not exists magic_links(
+    token varchar(64) primary key,
+    account_id uuid not null references accounts (id) on delete cascade,
+    browser_token varchar(64) not null,
+    user_agent text not null,
+    ip text not null,
+    expires_at timestamp with time zone not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+create index if not exists magic_links_account_id_idx on magic_links (account_id);
@@ -0,0 +1,4 @@
+create table if not exists account_email_otp(
+    account_id uuid primary key references accounts (id) on delete cascade,
+    created_at timestamp with time zone default current_timestamp not null
+);
@@ -0,0 +1 @@
+alter table accounts drop column if exists password_pepper_version;
@@ -0,0 +1 @@
+alter table accounts add column if not exists password_pepper_version smallint default 0 not null;
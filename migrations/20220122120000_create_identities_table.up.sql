@@ -0,0 +1,11 @@
+create table if not exists identities(
+    provider varchar(32) not null,
+    provider_user_id text not null,
+    account_id uuid not null references accounts (id) on delete cascade,
+    email varchar(255),
+    created_at timestamp with time zone default current_timestamp not null,
+    primary key (provider, provider_user_id),
+    unique (account_id, provider)
+);
+
+create index if not exists identities_account_id_idx on identities (account_id);
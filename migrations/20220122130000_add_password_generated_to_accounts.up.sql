@@ -0,0 +1,4 @@
+-- no backfill: accounts signed up via social login before this migration got random password hashed
+-- the same way as password chosen by user and signup provider is not stored, so they are indistinguishable
+-- from password accounts and keep the default, such users can still set password via password reset
+alter table accounts add column if not exists password_generated boolean default false not null;
@@ -7,7 +7,12 @@ var (
 	ErrAccountNotFound                 = errors.New("account not found")
 	ErrAccountNotArchived              = errors.New("account cannot be archived")
 	ErrAccountIncorrectEmailOrPassword = errors.New("incorrect email or password")
+	ErrAccountIncorrectLoginOrPassword = errors.New("incorrect login or password")
 	ErrAccountPasswordNotGenerated     = errors.New("password generation error")
 	ErrAccountIncorrectPassword        = errors.New("incorrect password")
 	ErrAccountContextNotFound          = errors.New("account not found in context")
+	ErrAccountNotVerified              = errors.New("account email is not verified")
+	ErrAccountAlreadyVerified          = errors.New("account is already verified")
+	ErrAccountVersionRequired          = errors.New("account version must be provided in If-Match header")
+	ErrAccountVersionConflict          = errors.New("account has been modified, reload it and try again")
 )
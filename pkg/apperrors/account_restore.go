@@ -0,0 +1,10 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrAccountRestoreTokenNotCreated = errors.New("error occured during account restore token creation")
+	ErrAccountRestoreTokenNotFound   = errors.New("account restore token is invalid or expired")
+	ErrAccountRestoreTokenExpired    = errors.New("account restore token expired")
+	ErrAccountRestorePeriodExpired   = errors.New("account restore period expired")
+)
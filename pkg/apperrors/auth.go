@@ -5,5 +5,11 @@ import "errors"
 var (
 	ErrAuthAccessDenied          = errors.New("access denied")
 	ErrAuthProviderNotFound      = errors.New("provider query parameter is missing")
+	ErrAuthProviderNotSupported  = errors.New("auth provider is not supported")
 	ErrAuthGitHubUserNotReceived = errors.New("cannot receive user from github api")
+	ErrAuthIDTokenNotReceived    = errors.New("id token is not received from provider")
+	ErrAuthIDTokenInvalid        = errors.New("invalid id token")
+	ErrAuthEmailNotVerified      = errors.New("email is not verified by provider")
+	ErrAuthEmailNotReceived      = errors.New("primary verified email is not received from provider")
+	ErrAuthAccountNotVerified    = errors.New("account with the same email is not verified")
 )
@@ -0,0 +1,10 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrEmailChangeTokenNotCreated = errors.New("error occured during email change token creation")
+	ErrEmailChangeNotFound        = errors.New("email change token is invalid or expired")
+	ErrEmailChangeExpired         = errors.New("email change token expired")
+	ErrEmailChangeSameEmail       = errors.New("new email is the same as current one")
+)
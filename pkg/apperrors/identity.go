@@ -0,0 +1,10 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrIdentityNotFound        = errors.New("identity not found")
+	ErrIdentityAlreadyExist    = errors.New("identity is already linked to account")
+	ErrIdentityLastLoginMethod = errors.New("identity is the last login method of account")
+	ErrIdentitySubjectNotFound = errors.New("user id is not received from provider")
+)
@@ -0,0 +1,9 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrMagicLinkNotCreated = errors.New("error occured during magic link creation")
+	ErrMagicLinkNotFound   = errors.New("magic link is invalid, already used or opened in another browser")
+	ErrMagicLinkExpired    = errors.New("magic link expired")
+)
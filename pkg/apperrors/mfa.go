@@ -0,0 +1,23 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrMFAChallengeNotCreated = errors.New("error occured during mfa challenge creation")
+	ErrMFAChallengeNotFound   = errors.New("mfa challenge not found or expired")
+	ErrMFAIncorrectCode       = errors.New("incorrect one-time password")
+	ErrMFAAlreadyEnabled      = errors.New("second factor is already enabled")
+	ErrMFANotEnabled          = errors.New("second factor is not enabled")
+	ErrMFATOTPNotFound        = errors.New("totp is not enrolled")
+	ErrMFAMethodNotAllowed    = errors.New("second factor method is not enabled for account")
+)
+
+var (
+	ErrMFAEmailCodeCooldown = errors.New("email code was sent recently, try again later")
+	ErrMFAEmailCodeExpired  = errors.New("email code expired or was not sent")
+)
+
+var (
+	ErrRecoveryCodesNotCreated = errors.New("error occured during recovery codes creation")
+	ErrRecoveryCodeNotFound    = errors.New("recovery code is invalid or already used")
+)
@@ -0,0 +1,9 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrOAuthStateNotCreated = errors.New("error occured during oauth state creation")
+	ErrOAuthStateNotFound   = errors.New("oauth state is invalid, already used or received in another browser")
+	ErrOAuthStateExpired    = errors.New("oauth state expired")
+)
@@ -0,0 +1,9 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrPasswordResetTokenNotCreated = errors.New("error occured during password reset token creation")
+	ErrPasswordResetTokenNotFound   = errors.New("password reset token is invalid or expired")
+	ErrPasswordResetTokenExpired    = errors.New("password reset token expired")
+)
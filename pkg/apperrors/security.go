@@ -7,3 +7,8 @@ var (
 	ErrCSRFTokenCookieNotFound = errors.New("csrf token not found in cookies")
 	ErrCSRFDetected            = errors.New("csrf tokens in headers and cookies are not the same")
 )
+
+var (
+	ErrTooManyRequests      = errors.New("too many requests, try again later")
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+)
@@ -0,0 +1,10 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrVerificationCodeNotCreated = errors.New("error occured during verification code creation")
+	ErrVerificationCodeNotFound   = errors.New("verification code not found")
+	ErrVerificationCodeExpired    = errors.New("verification code expired")
+	ErrVerificationCooldown       = errors.New("verification code was sent recently, try again later")
+)
@@ -0,0 +1,11 @@
+package apperrors
+
+import "errors"
+
+var (
+	ErrWebAuthnCeremonyNotCreated     = errors.New("error occured during webauthn ceremony creation")
+	ErrWebAuthnCeremonyNotFound       = errors.New("webauthn ceremony not found or expired")
+	ErrWebAuthnCredentialNotFound     = errors.New("webauthn credential not found")
+	ErrWebAuthnCredentialAlreadyExist = errors.New("webauthn credential is already registered")
+	ErrWebAuthnVerificationFailed     = errors.New("webauthn verification failed")
+)
@@ -0,0 +1,45 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type backgroundSender struct {
+	sender  Sender
+	onError func(error)
+	wg      sync.WaitGroup
+}
+
+// NewBackgroundSender creates sender which delivers messages with given sender off the request path,
+// Send returns immediately and delivery errors are passed to onError.
+// Response time of request which sends email does not depend on mail server then,
+// so it cannot reveal whether message was sent at all.
+func NewBackgroundSender(s Sender, onError func(error)) *backgroundSender {
+	return &backgroundSender{
+		sender:  s,
+		onError: onError,
+	}
+}
+
+// Send delivers message in separate goroutine, ctx is not passed to it
+// since request context is canceled as soon as response is written.
+func (s *backgroundSender) Send(ctx context.Context, to, subject, body string) error {
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.sender.Send(context.Background(), to, subject, body); err != nil {
+			s.onError(fmt.Errorf("s.sender.Send: %w", err))
+		}
+	}()
+
+	return nil
+}
+
+// Wait blocks until all messages being delivered are sent.
+func (s *backgroundSender) Wait() {
+	s.wg.Wait()
+}
@@ -0,0 +1,62 @@
+// Package email implements sending of email messages.
+package email
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Sender sends email messages.
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates sender which delivers messages through SMTP server,
+// auth is used only if username and password are provided.
+func NewSMTPSender(host, port, username, password, from string) *smtpSender {
+	s := &smtpSender{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+
+	if username != "" && password != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s
+}
+
+// Send sends plain text message to given address.
+func (s *smtpSender) Send(ctx context.Context, to, subject, body string) error {
+	msg := message(s.from, to, subject, body)
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{to}, msg); err != nil {
+		return fmt.Errorf("smtp.SendMail: %w", err)
+	}
+
+	return nil
+}
+
+// message returns plain text RFC 5322 message.
+func message(from, to, subject, body string) []byte {
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+
+	return []byte(msg.String())
+}
@@ -0,0 +1,39 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+type fileSender struct {
+	seq  uint64
+	dir  string
+	from string
+}
+
+// NewFileSender creates sender which writes each message to .eml file in given directory,
+// directory is created if it does not exist.
+func NewFileSender(dir, from string) (*fileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	return &fileSender{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+func (s *fileSender) Send(ctx context.Context, to, subject, body string) error {
+	name := fmt.Sprintf("%d-%d.eml", time.Now().UnixNano(), atomic.AddUint64(&s.seq, 1))
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), message(s.from, to, subject, body), 0o640); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+
+	return nil
+}
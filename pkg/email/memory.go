@@ -0,0 +1,58 @@
+package email
+
+import (
+	"context"
+	"sync"
+)
+
+// Message represents email message kept by in-memory sender.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type memorySender struct {
+	mu       sync.RWMutex
+	messages []Message
+}
+
+// NewMemorySender creates sender which keeps messages in memory instead of delivering them,
+// it is intended for tests and local development without mail server.
+func NewMemorySender() *memorySender {
+	return &memorySender{}
+}
+
+func (s *memorySender) Send(ctx context.Context, to, subject, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, Message{to, subject, body})
+
+	return nil
+}
+
+// Messages returns copy of all sent messages in order they were sent.
+func (s *memorySender) Messages() []Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := make([]Message, len(s.messages))
+	copy(messages, s.messages)
+
+	return messages
+}
+
+// Last returns last message sent to given address.
+func (s *memorySender) Last(to string) (Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].To == to {
+			return s.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
@@ -0,0 +1,65 @@
+// Package encryption implements symmetric encryption of data stored at rest.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidKey          = errors.New("encryption key must be 32 bytes hex encoded string")
+	ErrMalformedCipherText = errors.New("malformed cipher text")
+)
+
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM creates AES-256-GCM cipher using hex encoded key.
+func NewAESGCM(hexKey string) (aesGCM, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil || len(key) != 32 {
+		return aesGCM{}, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return aesGCM{}, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return aesGCM{}, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+
+	return aesGCM{aead}, nil
+}
+
+// Encrypt encrypts plaintext, random nonce is prepended to returned cipher text.
+func (c aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts cipher text produced by Encrypt.
+func (c aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	ns := c.aead.NonceSize()
+	if len(ciphertext) < ns {
+		return nil, ErrMalformedCipherText
+	}
+
+	return c.aead.Open(nil, ciphertext[:ns], ciphertext[ns:], nil)
+}
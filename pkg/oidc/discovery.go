@@ -0,0 +1,60 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+var ErrIssuerMismatch = errors.New("oidc: issuer of discovery document does not match configured issuer")
+
+// Metadata is OpenID Provider configuration received from discovery endpoint.
+type Metadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	UserinfoEndpoint              string   `json:"userinfo_endpoint"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// Discover fetches configuration of provider with given issuer URL.
+func Discover(ctx context.Context, client *http.Client, issuer string) (Metadata, error) {
+	u := strings.TrimSuffix(issuer, "/") + discoveryPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Metadata{}, fmt.Errorf("client.Do: unexpected status %d", resp.StatusCode)
+	}
+
+	var m Metadata
+
+	if err = json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return Metadata{}, fmt.Errorf("json.Decode: %w", err)
+	}
+
+	if m.Issuer != issuer {
+		return Metadata{}, fmt.Errorf("%w: %q", ErrIssuerMismatch, m.Issuer)
+	}
+
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return Metadata{}, fmt.Errorf("oidc: discovery document of %q is incomplete", issuer)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,268 @@
+// Package oidc verifies OpenID Connect ID tokens signed with keys published by provider in JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// jwksRefreshInterval limits how often keys are fetched when token is signed with unknown key.
+const jwksRefreshInterval = time.Minute
+
+var (
+	ErrInvalidToken       = errors.New("oidc: invalid id token")
+	ErrKeyNotFound        = errors.New("oidc: signing key not found")
+	ErrUnexpectedIssuer   = errors.New("oidc: unexpected issuer")
+	ErrUnexpectedAudience = errors.New("oidc: unexpected audience")
+)
+
+// Claims contains claims of ID token, user data is read from claims set in ClaimMapping.
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Raw           jwt.MapClaims
+}
+
+// Verifier verifies ID tokens issued for client by provider.
+type Verifier struct {
+	issuers  []string
+	clientID string
+	jwksURL  string
+	claims   ClaimMapping
+	client   *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+// NewVerifier creates verifier of ID tokens issued by any of issuers to client,
+// signing keys are fetched from jwksURL.
+func NewVerifier(issuers []string, clientID, jwksURL string, claims ClaimMapping) *Verifier {
+	return &Verifier{
+		issuers:  issuers,
+		clientID: clientID,
+		jwksURL:  jwksURL,
+		claims:   claims.withDefaults(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]interface{}),
+	}
+}
+
+// Verify checks signature, issuer, audience and expiration of raw ID token and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, rawIDToken string) (Claims, error) {
+	t, err := jwt.Parse(rawIDToken, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("%w: unexpected signing method %s", ErrInvalidToken, t.Method.Alg())
+		}
+
+		kid, _ := t.Header["kid"].(string)
+
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		// jwt.ValidationError does not support unwrapping, errors of key func are kept for callers
+		var ve *jwt.ValidationError
+		if errors.As(err, &ve) && (errors.Is(ve.Inner, ErrKeyNotFound) || errors.Is(ve.Inner, ErrInvalidToken)) {
+			return Claims{}, fmt.Errorf("jwt.Parse: %w", ve.Inner)
+		}
+
+		return Claims{}, fmt.Errorf("jwt.Parse: %s: %w", err, ErrInvalidToken)
+	}
+
+	mc, ok := t.Claims.(jwt.MapClaims)
+	if !ok || !t.Valid {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if !mc.VerifyExpiresAt(time.Now().Unix(), true) {
+		return Claims{}, fmt.Errorf("%w: token is expired or has no expiration", ErrInvalidToken)
+	}
+
+	c := Claims{Raw: mc}
+	c.Issuer, _ = mc["iss"].(string)
+	c.Subject, _ = mc["sub"].(string)
+	c.Email, _ = mc[v.claims.Email].(string)
+	c.Username, _ = mc[v.claims.Username].(string)
+
+	// Some providers encode email_verified as string
+	switch ev := mc[v.claims.EmailVerified].(type) {
+	case bool:
+		c.EmailVerified = ev
+	case string:
+		c.EmailVerified = ev == "true"
+	}
+
+	if !v.issuerAllowed(c.Issuer) {
+		return Claims{}, fmt.Errorf("%w: %q", ErrUnexpectedIssuer, c.Issuer)
+	}
+
+	if !mc.VerifyAudience(v.clientID, true) {
+		return Claims{}, ErrUnexpectedAudience
+	}
+
+	if c.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: subject is missing", ErrInvalidToken)
+	}
+
+	return c, nil
+}
+
+func (v *Verifier) issuerAllowed(iss string) bool {
+	for _, i := range v.issuers {
+		if i == iss {
+			return true
+		}
+	}
+
+	return false
+}
+
+// key returns public key by id, keys are fetched again if key is not found
+// since provider could rotate them.
+func (v *Verifier) key(ctx context.Context, kid string) (interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+
+	if time.Since(v.fetched) < jwksRefreshInterval {
+		return nil, ErrKeyNotFound
+	}
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v.keys = keys
+	v.fetched = time.Now()
+
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("v.client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("v.client.Do: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("json.Decode: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = pub
+	}
+
+	return keys, nil
+}
+
+var b64 = base64.RawURLEncoding
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := b64.DecodeString(k.E)
+		if err != nil || len(e) > 4 {
+			return nil, ErrInvalidToken
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrKeyNotFound
+		}
+
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, ErrInvalidToken
+		}
+
+		return pub, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
@@ -0,0 +1,368 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID = "client"
+	testKeyID    = "key-1"
+	testAlias    = "alias.example.com"
+)
+
+// fakeServer is local OpenID Connect provider which serves discovery document,
+// JWKS and token endpoint returning ID token set by test.
+type fakeServer struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	issuer    string
+	idToken   string
+	tokenForm url.Values
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	f := &fakeServer{t: t, key: newKey(t)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, f.discovery)
+	mux.HandleFunc("/jwks", f.jwks)
+	mux.HandleFunc("/token", f.token)
+
+	f.srv = httptest.NewServer(mux)
+	f.issuer = f.srv.URL
+	t.Cleanup(f.srv.Close)
+
+	return f
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+
+	return k
+}
+
+func (f *fakeServer) discovery(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.write(w, Metadata{
+		Issuer:                        f.issuer,
+		AuthorizationEndpoint:         f.srv.URL + "/authorize",
+		TokenEndpoint:                 f.srv.URL + "/token",
+		JWKSURI:                       f.srv.URL + "/jwks",
+		CodeChallengeMethodsSupported: []string{"S256"},
+	})
+}
+
+func (f *fakeServer) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := f.key.PublicKey
+
+	f.write(w, map[string][]jwk{"keys": {{
+		Kid: testKeyID,
+		Kty: "RSA",
+		Use: "sig",
+		N:   b64.EncodeToString(pub.N.Bytes()),
+		E:   b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (f *fakeServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tokenForm = r.PostForm
+
+	f.write(w, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     f.idToken,
+	})
+}
+
+func (f *fakeServer) write(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("json.Encode: %v", err)
+	}
+}
+
+// claims returns valid claims of ID token issued by the server.
+func (f *fakeServer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                f.srv.URL,
+		"sub":                "user-1",
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"email":              "user@example.com",
+		"email_verified":     true,
+		"preferred_username": "user",
+	}
+}
+
+// sign returns ID token with claims signed by key with key id.
+func (f *fakeServer) sign(c jwt.MapClaims, key *rsa.PrivateKey, kid string) string {
+	f.t.Helper()
+
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	t.Header["kid"] = kid
+
+	raw, err := t.SignedString(key)
+	if err != nil {
+		f.t.Fatalf("t.SignedString: %v", err)
+	}
+
+	return raw
+}
+
+func (f *fakeServer) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:        f.srv.URL,
+		IssuerAliases: []string{testAlias},
+		ClientID:      testClientID,
+		ClientSecret:  "secret",
+		RedirectURL:   "http://localhost:3000/login/test",
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	f := newFakeServer(t)
+
+	m, err := Discover(context.Background(), http.DefaultClient, f.srv.URL)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	if m.TokenEndpoint != f.srv.URL+"/token" || m.JWKSURI != f.srv.URL+"/jwks" {
+		t.Fatalf("metadata = %+v", m)
+	}
+
+	f.issuer = "https://evil.example.com"
+
+	if _, err = Discover(context.Background(), http.DefaultClient, f.srv.URL); !errors.Is(err, ErrIssuerMismatch) {
+		t.Fatalf("Discover error = %v, want %v", err, ErrIssuerMismatch)
+	}
+}
+
+func TestProviderCodeExchange(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeServer(t)
+	p := f.provider()
+
+	o, err := p.OAuth2Config(ctx)
+	if err != nil {
+		t.Fatalf("OAuth2Config: %v", err)
+	}
+
+	u, err := url.Parse(o.AuthCodeURL("state"))
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	if got := u.Query().Get("scope"); got != scopeOpenID {
+		t.Fatalf("scope = %q, want %q", got, scopeOpenID)
+	}
+
+	f.idToken = f.sign(f.claims(), f.key, testKeyID)
+
+	tok, err := o.Exchange(ctx, "code", oauth2.SetAuthURLParam("code_verifier", "verifier"))
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if got := f.tokenForm.Get("code_verifier"); got != "verifier" {
+		t.Fatalf("code_verifier = %q, want %q", got, "verifier")
+	}
+
+	raw, _ := tok.Extra("id_token").(string)
+
+	c, err := p.Verify(ctx, raw)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	if c.Subject != "user-1" || c.Email != "user@example.com" || !c.EmailVerified || c.Username != "user" {
+		t.Fatalf("claims = %+v", c)
+	}
+}
+
+func TestVerifyRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   func(f *fakeServer) string
+		wantErr error
+	}{
+		{
+			name: "bad signature",
+			token: func(f *fakeServer) string {
+				return f.sign(f.claims(), newKey(f.t), testKeyID)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "unknown kid",
+			token: func(f *fakeServer) string {
+				return f.sign(f.claims(), f.key, "key-2")
+			},
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name: "wrong aud",
+			token: func(f *fakeServer) string {
+				c := f.claims()
+				c["aud"] = "other-client"
+				return f.sign(c, f.key, testKeyID)
+			},
+			wantErr: ErrUnexpectedAudience,
+		},
+		{
+			name: "wrong iss",
+			token: func(f *fakeServer) string {
+				c := f.claims()
+				c["iss"] = "https://evil.example.com"
+				return f.sign(c, f.key, testKeyID)
+			},
+			wantErr: ErrUnexpectedIssuer,
+		},
+		{
+			name: "iss without scheme which is not alias",
+			token: func(f *fakeServer) string {
+				c := f.claims()
+				c["iss"] = "evil.example.com"
+				return f.sign(c, f.key, testKeyID)
+			},
+			wantErr: ErrUnexpectedIssuer,
+		},
+		{
+			name: "expired",
+			token: func(f *fakeServer) string {
+				c := f.claims()
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+				return f.sign(c, f.key, testKeyID)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "no expiration",
+			token: func(f *fakeServer) string {
+				c := f.claims()
+				delete(c, "exp")
+				return f.sign(c, f.key, testKeyID)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "no subject",
+			token: func(f *fakeServer) string {
+				c := f.claims()
+				delete(c, "sub")
+				return f.sign(c, f.key, testKeyID)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "hmac signed",
+			token: func(f *fakeServer) string {
+				t := jwt.NewWithClaims(jwt.SigningMethodHS256, f.claims())
+				t.Header["kid"] = testKeyID
+
+				raw, err := t.SignedString([]byte("secret"))
+				if err != nil {
+					f.t.Fatalf("t.SignedString: %v", err)
+				}
+
+				return raw
+			},
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t)
+
+			if _, err := f.provider().Verify(context.Background(), tt.token(f)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIssuerAlias(t *testing.T) {
+	f := newFakeServer(t)
+
+	c := f.claims()
+	c["iss"] = testAlias
+
+	if _, err := f.provider().Verify(context.Background(), f.sign(c, f.key, testKeyID)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestVerifyEmailVerified(t *testing.T) {
+	tests := []struct {
+		name          string
+		emailVerified interface{}
+		want          bool
+	}{
+		{"bool true", true, true},
+		{"string true", "true", true},
+		{"bool false", false, false},
+		{"string false", "false", false},
+		{"missing", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t)
+
+			c := f.claims()
+			if tt.emailVerified == nil {
+				delete(c, "email_verified")
+			} else {
+				c["email_verified"] = tt.emailVerified
+			}
+
+			got, err := f.provider().Verify(context.Background(), f.sign(c, f.key, testKeyID))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			// email which is not verified must not be used to find account
+			if got.EmailVerified != tt.want {
+				t.Fatalf("EmailVerified = %v, want %v", got.EmailVerified, tt.want)
+			}
+		})
+	}
+}
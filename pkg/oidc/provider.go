@@ -0,0 +1,143 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const scopeOpenID = "openid"
+
+// ClaimMapping contains names of ID token claims which hold user data,
+// empty names fall back to standard claims.
+type ClaimMapping struct {
+	Email         string
+	EmailVerified string
+	Username      string
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	if m.Email == "" {
+		m.Email = "email"
+	}
+
+	if m.EmailVerified == "" {
+		m.EmailVerified = "email_verified"
+	}
+
+	if m.Username == "" {
+		m.Username = "preferred_username"
+	}
+
+	return m
+}
+
+// Config of OpenID Connect client, IssuerAliases are accepted in iss claim along with Issuer.
+type Config struct {
+	Issuer        string
+	IssuerAliases []string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	Claims        ClaimMapping
+}
+
+// Provider is OpenID Connect provider configured with discovery,
+// discovery is performed on first use and retried until succeeded.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	meta     *Metadata
+	oauth2   *oauth2.Config
+	verifier *Verifier
+}
+
+func NewProvider(cfg Config) *Provider {
+	cfg.Claims = cfg.Claims.withDefaults()
+
+	hasOpenID := false
+	for _, s := range cfg.Scopes {
+		if s == scopeOpenID {
+			hasOpenID = true
+		}
+	}
+
+	if !hasOpenID {
+		cfg.Scopes = append([]string{scopeOpenID}, cfg.Scopes...)
+	}
+
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Metadata returns provider configuration received from discovery endpoint.
+func (p *Provider) Metadata(ctx context.Context) (Metadata, error) {
+	if err := p.discover(ctx); err != nil {
+		return Metadata{}, err
+	}
+
+	return *p.meta, nil
+}
+
+// OAuth2Config returns OAuth2 client configuration with endpoints of the provider.
+func (p *Provider) OAuth2Config(ctx context.Context) (*oauth2.Config, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	o := *p.oauth2
+
+	return &o, nil
+}
+
+// Verify verifies raw ID token issued by the provider and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string) (Claims, error) {
+	if err := p.discover(ctx); err != nil {
+		return Claims{}, err
+	}
+
+	return p.verifier.Verify(ctx, rawIDToken)
+}
+
+func (p *Provider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return nil
+	}
+
+	m, err := Discover(ctx, p.client, p.cfg.Issuer)
+	if err != nil {
+		return fmt.Errorf("Discover: %w", err)
+	}
+
+	p.meta = &m
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  m.AuthorizationEndpoint,
+			TokenURL: m.TokenEndpoint,
+		},
+	}
+	p.verifier = NewVerifier(
+		append([]string{m.Issuer}, p.cfg.IssuerAliases...),
+		p.cfg.ClientID,
+		m.JWKSURI,
+		p.cfg.Claims,
+	)
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$" + AlgorithmArgon2id + "$"
+
+// Bounds of parameters decoded from stored hash, argon2.IDKey panics with zero iterations
+// or parallelism and hash with huge memory or iterations would exhaust server on login.
+const (
+	argon2idMaxMemory     = 1 << 20 // 1 GiB
+	argon2idMaxIterations = 64
+	argon2idMinKeyLength  = 16
+	argon2idMaxKeyLength  = 1024
+)
+
+var b64 = base64.RawStdEncoding
+
+// Argon2idParams contains cost parameters of argon2id, memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2idScheme struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(p Argon2idParams) *argon2idScheme {
+	return &argon2idScheme{params: p}
+}
+
+// Hash returns hash in format $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func (s *argon2idScheme) Hash(password string) (string, error) {
+	salt := make([]byte, s.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+
+	p := s.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		p.Memory,
+		p.Iterations,
+		p.Parallelism,
+		b64.EncodeToString(salt),
+		b64.EncodeToString(key),
+	), nil
+}
+
+func (s *argon2idScheme) Verify(hash, password string) error {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (s *argon2idScheme) Match(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (s *argon2idScheme) Outdated(hash string) bool {
+	p, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory < s.params.Memory ||
+		p.Iterations < s.params.Iterations ||
+		p.Parallelism < s.params.Parallelism ||
+		p.SaltLength < s.params.SaltLength ||
+		p.KeyLength < s.params.KeyLength
+}
+
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	if version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedVersion
+	}
+
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	if p.Parallelism == 0 || p.Iterations == 0 || p.Iterations > argon2idMaxIterations ||
+		p.Memory < 8*uint32(p.Parallelism) || p.Memory > argon2idMaxMemory {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) < argon2idMinKeyLength || len(key) > argon2idMaxKeyLength {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,47 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArgon2idVerifyRejectsOutOfRangeParams(t *testing.T) {
+	s := NewArgon2id(Argon2idParams{
+		Memory:      64,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+
+	hash, err := s.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	if err = s.Verify(hash, "password"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params string
+	}{
+		{"zero iterations", "m=64,t=0,p=1"},
+		{"zero parallelism", "m=64,t=1,p=0"},
+		{"too many iterations", "m=64,t=100000,p=1"},
+		{"too little memory", "m=8,t=1,p=4"},
+		{"too much memory", "m=4294967295,t=1,p=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := strings.Replace(hash, "m=64,t=1,p=1", tt.params, 1)
+
+			if err := s.Verify(h, "password"); !errors.Is(err, ErrInvalidHash) {
+				t.Fatalf("Verify error = %v, want %v", err, ErrInvalidHash)
+			}
+		})
+	}
+}
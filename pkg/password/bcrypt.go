@@ -0,0 +1,47 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptScheme struct {
+	cost int
+}
+
+func NewBcrypt(cost int) *bcryptScheme {
+	return &bcryptScheme{cost: cost}
+}
+
+func (s *bcryptScheme) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (s *bcryptScheme) Verify(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+
+	return err
+}
+
+func (s *bcryptScheme) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (s *bcryptScheme) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost < s.cost
+}
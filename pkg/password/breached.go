@@ -0,0 +1,70 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const breachedPrefixLen = 5
+
+type breachedDir struct {
+	dir string
+}
+
+// NewBreachedDir creates breached passwords list stored on disk in k-anonymity range format,
+// the same as used by Have I Been Pwned: file <dir>/<PREFIX>.txt contains lines SUFFIX:COUNT
+// where PREFIX is first 5 and SUFFIX is the rest of upper case hex SHA-1 hash of password.
+// Only one small file is read on every check.
+func NewBreachedDir(dir string) (*breachedDir, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat: %w", err)
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return &breachedDir{dir: dir}, nil
+}
+
+func (b *breachedDir) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	h := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	f, err := os.Open(filepath.Join(b.dir, h[:breachedPrefixLen]+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	suffix := h[breachedPrefixLen:]
+	s := bufio.NewScanner(f)
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+
+	if err = s.Err(); err != nil {
+		return false, fmt.Errorf("s.Err: %w", err)
+	}
+
+	return false, nil
+}
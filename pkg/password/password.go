@@ -0,0 +1,65 @@
+// Package password implements hashing of passwords with pluggable schemes,
+// hashes are encoded in PHC string format, bcrypt hashes keep their own modular crypt format.
+package password
+
+import "errors"
+
+// Supported hashing algorithms.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatch           = errors.New("password does not match hash")
+	ErrInvalidHash        = errors.New("invalid password hash encoding")
+	ErrUnsupportedHash    = errors.New("password hash algorithm is not supported")
+	ErrUnsupportedVersion = errors.New("password hash algorithm version is not supported")
+)
+
+// Scheme hashes passwords with single algorithm.
+type Scheme interface {
+	Hash(password string) (string, error)
+
+	// Verify returns ErrMismatch if password does not match hash.
+	Verify(hash, password string) error
+
+	// Match reports whether hash is made by the scheme.
+	Match(hash string) bool
+
+	// Outdated reports whether hash made by the scheme uses weaker parameters than configured.
+	Outdated(hash string) bool
+}
+
+// Hasher hashes passwords with preferred scheme and verifies hashes made by any of known schemes,
+// so hashes can be migrated to preferred scheme after successful verification.
+type Hasher struct {
+	preferred Scheme
+	schemes   []Scheme
+}
+
+func NewHasher(preferred Scheme, legacy ...Scheme) *Hasher {
+	return &Hasher{
+		preferred: preferred,
+		schemes:   append([]Scheme{preferred}, legacy...),
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *Hasher) Verify(hash, password string) error {
+	for _, s := range h.schemes {
+		if s.Match(hash) {
+			return s.Verify(hash, password)
+		}
+	}
+
+	return ErrUnsupportedHash
+}
+
+// NeedsRehash reports whether hash is not made by preferred scheme or uses its weaker parameters.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	return !h.preferred.Match(hash) || h.preferred.Outdated(hash)
+}
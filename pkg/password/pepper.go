@@ -0,0 +1,85 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const pepperKeyMinLen = 32
+
+var (
+	ErrInvalidPepperKey = errors.New("pepper key must be at least 32 bytes hex encoded string")
+	ErrPepperNotFound   = errors.New("pepper key with given version not found")
+)
+
+// Pepper keys passwords with secret kept outside of database before hashing,
+// so leaked hashes cannot be cracked without the key.
+// Keys are versioned to be rotated, version 0 means password is not peppered.
+// Nil pepper does not pepper new passwords.
+type Pepper struct {
+	version int
+	keys    map[int][]byte
+}
+
+// NewPepper creates pepper which uses key with version for new passwords,
+// other keys are used to verify passwords peppered before rotation.
+func NewPepper(version int, hexKeys map[int]string) (*Pepper, error) {
+	p := &Pepper{
+		version: version,
+		keys:    make(map[int][]byte, len(hexKeys)),
+	}
+
+	for v, hk := range hexKeys {
+		k, err := hex.DecodeString(hk)
+		if err != nil || len(k) < pepperKeyMinLen || v <= 0 {
+			return nil, fmt.Errorf("version %d: %w", v, ErrInvalidPepperKey)
+		}
+
+		p.keys[v] = k
+	}
+
+	if _, ok := p.keys[version]; version != 0 && !ok {
+		return nil, fmt.Errorf("version %d: %w", version, ErrPepperNotFound)
+	}
+
+	return p, nil
+}
+
+// Version returns version of key used for new passwords.
+func (p *Pepper) Version() int {
+	if p == nil {
+		return 0
+	}
+
+	return p.version
+}
+
+// Apply returns password keyed with HMAC-SHA256 using key of given version.
+func (p *Pepper) Apply(password string, version int) (string, error) {
+	if version == 0 {
+		return password, nil
+	}
+
+	if p == nil {
+		return "", fmt.Errorf("version %d: %w", version, ErrPepperNotFound)
+	}
+
+	k, ok := p.keys[version]
+	if !ok {
+		return "", fmt.Errorf("version %d: %w", version, ErrPepperNotFound)
+	}
+
+	mac := hmac.New(sha256.New, k)
+	mac.Write([]byte(password))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Stale reports whether password peppered with version must be peppered with current key.
+func (p *Pepper) Stale(version int) bool {
+	return version != p.Version()
+}
@@ -0,0 +1,133 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+// Rules of password policy.
+const (
+	RuleStrength     = "strength"
+	RuleDenylist     = "denylist"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// personalInfoMinLen is minimal length of username or email part which is not allowed in password.
+const personalInfoMinLen = 3
+
+var ErrPolicyViolation = errors.New("password violates policy")
+
+// PolicyError contains messages of failed rules by rule name.
+type PolicyError struct {
+	Failures map[string]string
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, 0, len(e.Failures))
+	for r := range e.Failures {
+		rules = append(rules, r)
+	}
+
+	sort.Strings(rules)
+
+	return fmt.Sprintf("%s: %s", ErrPolicyViolation, strings.Join(rules, ", "))
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+// BreachedList reports whether password is known from data breaches.
+type BreachedList interface {
+	Breached(password string) (bool, error)
+}
+
+// Policy checks strength of new passwords.
+type Policy struct {
+	minScore   int
+	minEntropy float64
+	denylist   map[string]struct{}
+	breached   BreachedList
+}
+
+// NewPolicy creates policy which requires zxcvbn score of at least minScore (0-4)
+// and entropy of at least minEntropy bits, denylist is case insensitive,
+// nil breached list disables breached passwords check.
+func NewPolicy(minScore int, minEntropy float64, denylist []string, b BreachedList) *Policy {
+	p := &Policy{
+		minScore:   minScore,
+		minEntropy: minEntropy,
+		denylist:   make(map[string]struct{}, len(denylist)),
+		breached:   b,
+	}
+
+	for _, d := range denylist {
+		p.denylist[strings.ToLower(d)] = struct{}{}
+	}
+
+	return p
+}
+
+// Check returns *PolicyError with all failed rules, personal is account data
+// such as username and email which must not be reused in password.
+func (p *Policy) Check(password string, personal ...string) error {
+	failures := make(map[string]string)
+
+	res := zxcvbn.PasswordStrength(password, personal)
+	if res.Score < p.minScore || res.Entropy < p.minEntropy {
+		failures[RuleStrength] = "Password is too weak, add more words or uncommon characters."
+	}
+
+	lower := strings.ToLower(password)
+
+	if _, ok := p.denylist[lower]; ok {
+		failures[RuleDenylist] = "Password is too common."
+	}
+
+	if containsPersonalInfo(lower, personal) {
+		failures[RulePersonalInfo] = "Password must not contain username or email."
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Breached(password)
+		if err != nil {
+			return fmt.Errorf("p.breached.Breached: %w", err)
+		}
+
+		if breached {
+			failures[RuleBreached] = "Password has appeared in a data breach, choose another one."
+		}
+	}
+
+	if len(failures) > 0 {
+		return &PolicyError{failures}
+	}
+
+	return nil
+}
+
+// containsPersonalInfo reports whether lowercased password contains any of personal values,
+// emails are checked both whole and by local part.
+func containsPersonalInfo(password string, personal []string) bool {
+	for _, v := range personal {
+		v = strings.ToLower(v)
+
+		values := []string{v}
+		if i := strings.LastIndex(v, "@"); i > 0 {
+			values = append(values, v[:i])
+		}
+
+		for _, s := range values {
+			if len(s) >= personalInfoMinLen && strings.Contains(password, s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+// Package ratelimit implements token bucket rate limiters.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Result represents result of taking token from bucket.
+type Result struct {
+	Allowed bool
+
+	// Limit is bucket capacity.
+	Limit int
+
+	// Remaining is number of tokens left in bucket.
+	Remaining int
+
+	// RetryAfter is time until next token is available, zero if request is allowed.
+	RetryAfter time.Duration
+
+	// ResetAfter is time until bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Limiter takes tokens from bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// NewFunc creates limiter for named policy which allows limit requests per period,
+// name separates buckets of different policies kept in shared storage.
+type NewFunc func(name string, limit int, period time.Duration) Limiter
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type memoryLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	rate    float64 // tokens per second
+	buckets map[string]*bucket
+	cleaned time.Time
+}
+
+// NewMemory creates in-memory limiter which allows limit requests per period
+// with burst up to limit.
+func NewMemory(limit int, period time.Duration) *memoryLimiter {
+	return &memoryLimiter{
+		limit:   limit,
+		rate:    float64(limit) / period.Seconds(),
+		buckets: make(map[string]*bucket),
+		cleaned: time.Now(),
+	}
+}
+
+func (l *memoryLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit), updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.limit), b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	res := Result{Limit: l.limit}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = refillDuration(1-b.tokens, l.rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = refillDuration(float64(l.limit)-b.tokens, l.rate)
+
+	return res, nil
+}
+
+// cleanup removes full buckets once in a while to not keep every key in memory forever.
+func (l *memoryLimiter) cleanup(now time.Time) {
+	if now.Sub(l.cleaned) < time.Minute {
+		return
+	}
+
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= float64(l.limit) {
+			delete(l.buckets, k)
+		}
+	}
+
+	l.cleaned = now
+}
+
+// refillDuration returns time needed to refill given number of tokens with rate tokens per second.
+func refillDuration(tokens, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
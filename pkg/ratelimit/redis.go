@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeScript refills bucket stored in hash by elapsed time and takes one token from it,
+// bucket expires when it is full again.
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local b = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(b[1]) or limit
+local updated = tonumber(b[2]) or now
+
+tokens = math.min(limit, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], math.max(1, math.ceil((limit - tokens) / rate)))
+
+return {allowed, tostring(tokens)}
+`)
+
+type redisLimiter struct {
+	*redis.Client
+	prefix string
+	limit  int
+	rate   float64 // tokens per second
+}
+
+// NewRedis creates limiter which keeps buckets in redis so limits are shared between instances,
+// it allows limit requests per period with burst up to limit.
+// Buckets are refilled using clock of the instance, so clocks must be in sync.
+func NewRedis(rdb *redis.Client, prefix string, limit int, period time.Duration) *redisLimiter {
+	return &redisLimiter{
+		Client: rdb,
+		prefix: prefix,
+		limit:  limit,
+		rate:   float64(limit) / period.Seconds(),
+	}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	v, err := takeScript.Run(
+		ctx,
+		l.Client,
+		[]string{l.prefix + key},
+		l.limit,
+		strconv.FormatFloat(l.rate/1000, 'g', -1, 64),
+		time.Now().UnixNano()/int64(time.Millisecond),
+	).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("takeScript.Run: %w", err)
+	}
+
+	if len(v) != 2 {
+		return Result{}, fmt.Errorf("takeScript.Run: unexpected result %v", v)
+	}
+
+	allowed, _ := v[0].(int64)
+	s, _ := v[1].(string)
+
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("strconv.ParseFloat: %w", err)
+	}
+
+	res := Result{
+		Allowed:    allowed == 1,
+		Limit:      l.limit,
+		Remaining:  int(tokens),
+		ResetAfter: refillDuration(float64(l.limit)-tokens, l.rate),
+	}
+
+	if !res.Allowed {
+		res.RetryAfter = refillDuration(1-tokens, l.rate)
+	}
+
+	return res, nil
+}
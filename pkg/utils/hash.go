@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SHA256 returns hex encoded SHA-256 hash of given string.
+func SHA256(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// HMACSHA256 returns hex encoded HMAC-SHA-256 of given string with key.
+func HMACSHA256(key, s string) string {
+	m := hmac.New(sha256.New, []byte(key))
+	m.Write([]byte(s))
+	return hex.EncodeToString(m.Sum(nil))
+}
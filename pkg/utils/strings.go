@@ -2,6 +2,8 @@ package utils
 
 import (
 	cryptoRand "crypto/rand"
+	"fmt"
+	"math/big"
 	mathRand "math/rand"
 )
 
@@ -25,6 +27,19 @@ func UniqueString(length int) (string, error) {
 	return string(bytes), nil
 }
 
+// UniqueDigits generates random string of decimal digits using
+// Cryptographically Secure Pseudorandom number, leading zeros are kept.
+func UniqueDigits(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+
+	n, err := cryptoRand.Int(cryptoRand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
 // RandomString generates random URL safe string.
 func RandomString(length int) string {
 	bytes := make([]byte, length)
@@ -41,7 +56,7 @@ func RandomSpecialString(length int) string {
 
 	c := chars + special
 	for i := range bytes {
-		bytes[i] = c[mathRand.Intn(len(chars))]
+		bytes[i] = c[mathRand.Intn(len(c))]
 	}
 
 	return string(bytes)
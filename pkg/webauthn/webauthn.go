@@ -0,0 +1,325 @@
+// Package webauthn implements relying party side of W3C Web Authentication ceremonies.
+// Only "none" attestation is accepted and credentials must use ES256 or RS256 algorithm,
+// assertions require user presence and user verification.
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ugorji/go/codec"
+)
+
+// COSE algorithm identifiers of supported credential public keys.
+const (
+	AlgES256 = -7
+	AlgRS256 = -257
+)
+
+const (
+	challengeSize = 32
+
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredData   = 0x40
+	authDataMinLen         = 37
+	attestedCredDataMinLen = 18
+
+	typeCreate = "webauthn.create"
+	typeGet    = "webauthn.get"
+)
+
+var (
+	ErrInvalidClientData        = errors.New("webauthn: invalid client data")
+	ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+	ErrUnsupportedAttestation   = errors.New("webauthn: unsupported attestation format")
+	ErrUnsupportedAlgorithm     = errors.New("webauthn: unsupported public key algorithm")
+	ErrInvalidSignature         = errors.New("webauthn: invalid signature")
+	ErrCloned                   = errors.New("webauthn: signature counter did not increase, authenticator may be cloned")
+)
+
+// Encoding is used for challenges and credential ids on the wire.
+var Encoding = base64.RawURLEncoding
+
+var cbor = &codec.CborHandle{}
+
+func init() {
+	cbor.SignedInteger = true
+}
+
+// RelyingParty verifies ceremonies performed by authenticators for a single origin.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+func New(id, name, origin string) *RelyingParty {
+	return &RelyingParty{
+		ID:     id,
+		Name:   name,
+		Origin: origin,
+	}
+}
+
+// Credential represents public key credential created by authenticator,
+// public key is PKIX, ASN.1 DER encoded.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+// NewChallenge returns random base64url encoded challenge.
+func NewChallenge() (string, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return Encoding.EncodeToString(b), nil
+}
+
+// VerifyRegistration verifies response of navigator.credentials.create
+// and returns created credential.
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, typeCreate, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	var att struct {
+		Format   string                 `codec:"fmt"`
+		AttStmt  map[string]interface{} `codec:"attStmt"`
+		AuthData []byte                 `codec:"authData"`
+	}
+
+	if err := codec.NewDecoderBytes(attestationObject, cbor).Decode(&att); err != nil {
+		return Credential{}, fmt.Errorf("%w: %s", ErrInvalidAuthenticatorData, err)
+	}
+
+	if att.Format != "none" || len(att.AttStmt) != 0 {
+		return Credential{}, ErrUnsupportedAttestation
+	}
+
+	ad, err := rp.parseAuthData(att.AuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if ad.flags&flagAttestedCredData == 0 {
+		return Credential{}, fmt.Errorf("%w: attested credential data not included", ErrInvalidAuthenticatorData)
+	}
+
+	data := ad.rest
+	if len(data) < attestedCredDataMinLen {
+		return Credential{}, fmt.Errorf("%w: attested credential data too short", ErrInvalidAuthenticatorData)
+	}
+
+	idLen := int(binary.BigEndian.Uint16(data[16:18]))
+	data = data[attestedCredDataMinLen:]
+
+	if len(data) < idLen {
+		return Credential{}, fmt.Errorf("%w: credential id too short", ErrInvalidAuthenticatorData)
+	}
+
+	id := data[:idLen]
+
+	pub, err := parseCOSEKey(data[idLen:])
+	if err != nil {
+		return Credential{}, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, err)
+	}
+
+	return Credential{
+		ID:        id,
+		PublicKey: der,
+		SignCount: ad.signCount,
+	}, nil
+}
+
+// VerifyAssertion verifies response of navigator.credentials.get made by credential
+// with given public key and stored signature counter and returns new signature counter.
+func (rp *RelyingParty) VerifyAssertion(challenge string, publicKey []byte, signCount uint32,
+	clientDataJSON, authenticatorData, signature []byte) (uint32, error) {
+
+	if err := rp.verifyClientData(clientDataJSON, typeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	ad, err := rp.parseAuthData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	if ad.flags&flagUserVerified == 0 {
+		return 0, fmt.Errorf("%w: user not verified", ErrInvalidAuthenticatorData)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return 0, fmt.Errorf("x509.ParsePKIXPublicKey: %w", err)
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], signature) {
+			return 0, ErrInvalidSignature
+		}
+	case *rsa.PublicKey:
+		if err = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature); err != nil {
+			return 0, ErrInvalidSignature
+		}
+	default:
+		return 0, ErrUnsupportedAlgorithm
+	}
+
+	// Authenticators which do not support counters always return zero
+	if (ad.signCount != 0 || signCount != 0) && ad.signCount <= signCount {
+		return 0, ErrCloned
+	}
+
+	return ad.signCount, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, typ, challenge string) error {
+	var cd clientData
+
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidClientData, err)
+	}
+
+	if cd.Type != typ {
+		return fmt.Errorf("%w: unexpected type %q", ErrInvalidClientData, cd.Type)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidClientData)
+	}
+
+	if cd.Origin != rp.Origin {
+		return fmt.Errorf("%w: unexpected origin %q", ErrInvalidClientData, cd.Origin)
+	}
+
+	return nil
+}
+
+type authData struct {
+	flags     byte
+	signCount uint32
+	rest      []byte
+}
+
+func (rp *RelyingParty) parseAuthData(b []byte) (authData, error) {
+	if len(b) < authDataMinLen {
+		return authData{}, fmt.Errorf("%w: too short", ErrInvalidAuthenticatorData)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(b[:32], rpIDHash[:]) {
+		return authData{}, fmt.Errorf("%w: rp id hash mismatch", ErrInvalidAuthenticatorData)
+	}
+
+	ad := authData{
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+		rest:      b[37:],
+	}
+
+	if ad.flags&flagUserPresent == 0 {
+		return authData{}, fmt.Errorf("%w: user not present", ErrInvalidAuthenticatorData)
+	}
+
+	return ad, nil
+}
+
+// COSE key parameters, see RFC 8152.
+const (
+	coseKty = 1
+	coseAlg = 3
+
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256 = 1
+
+	coseEC2Crv = -1
+	coseEC2X   = -2
+	coseEC2Y   = -3
+
+	coseRSAN = -1
+	coseRSAE = -2
+)
+
+func parseCOSEKey(b []byte) (crypto.PublicKey, error) {
+	var k map[int]interface{}
+
+	if err := codec.NewDecoderBytes(b, cbor).Decode(&k); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAuthenticatorData, err)
+	}
+
+	kty, _ := k[coseKty].(int64)
+	alg, _ := k[coseAlg].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := k[coseEC2Crv].(int64)
+		x, _ := k[coseEC2X].([]byte)
+		y, _ := k[coseEC2Y].([]byte)
+
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, ErrUnsupportedAlgorithm
+		}
+
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, ErrUnsupportedAlgorithm
+		}
+
+		return pub, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := k[coseRSAN].([]byte)
+		e, _ := k[coseRSAE].([]byte)
+
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrUnsupportedAlgorithm
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+
+	return nil, ErrUnsupportedAlgorithm
+}
@@ -0,0 +1,341 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+)
+
+// authenticator is software ES256 authenticator which performs ceremonies
+// the same way as browser with platform authenticator.
+type authenticator struct {
+	t         *testing.T
+	id        []byte
+	key       *ecdsa.PrivateKey
+	rpID      string
+	origin    string
+	flags     byte
+	signCount uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey: %v", err)
+	}
+
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	return &authenticator{
+		t:      t,
+		id:     id,
+		key:    key,
+		rpID:   testRPID,
+		origin: testOrigin,
+		flags:  flagUserPresent | flagUserVerified,
+	}
+}
+
+func (a *authenticator) clientData(typ, challenge string) []byte {
+	a.t.Helper()
+
+	b, err := json.Marshal(clientData{Type: typ, Challenge: challenge, Origin: a.origin})
+	if err != nil {
+		a.t.Fatalf("json.Marshal: %v", err)
+	}
+
+	return b
+}
+
+func (a *authenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	b := append([]byte{}, rpIDHash[:]...)
+	b = append(b, flags)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[33:37], a.signCount)
+
+	return append(b, attested...)
+}
+
+func (a *authenticator) encode(v interface{}) []byte {
+	a.t.Helper()
+
+	var b []byte
+
+	if err := codec.NewEncoderBytes(&b, cbor).Encode(v); err != nil {
+		a.t.Fatalf("codec.Encode: %v", err)
+	}
+
+	return b
+}
+
+// create returns clientDataJSON and attestationObject of navigator.credentials.create.
+func (a *authenticator) create(challenge string) ([]byte, []byte) {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+
+	coseKey := a.encode(map[int]interface{}{
+		coseKty:    coseKtyEC2,
+		coseAlg:    AlgES256,
+		coseEC2Crv: coseCrvP256,
+		coseEC2X:   x,
+		coseEC2Y:   y,
+	})
+
+	attested := make([]byte, attestedCredDataMinLen)
+	binary.BigEndian.PutUint16(attested[16:18], uint16(len(a.id)))
+	attested = append(attested, a.id...)
+	attested = append(attested, coseKey...)
+
+	attObj := a.encode(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(a.flags|flagAttestedCredData, attested),
+	})
+
+	return a.clientData(typeCreate, challenge), attObj
+}
+
+// get returns clientDataJSON, authenticatorData and signature of navigator.credentials.get,
+// signature counter is incremented before signing.
+func (a *authenticator) get(challenge string) ([]byte, []byte, []byte) {
+	a.t.Helper()
+
+	a.signCount++
+
+	cdj := a.clientData(typeGet, challenge)
+	ad := a.authData(a.flags, nil)
+
+	clientDataHash := sha256.Sum256(cdj)
+	digest := sha256.Sum256(append(append([]byte{}, ad...), clientDataHash[:]...))
+
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("ecdsa.SignASN1: %v", err)
+	}
+
+	return cdj, ad, sig
+}
+
+func newChallenge(t *testing.T) string {
+	t.Helper()
+
+	c, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+
+	return c
+}
+
+// register registers credential of authenticator and returns it.
+func register(t *testing.T, rp *RelyingParty, a *authenticator) Credential {
+	t.Helper()
+
+	challenge := newChallenge(t)
+	cdj, attObj := a.create(challenge)
+
+	c, err := rp.VerifyRegistration(challenge, cdj, attObj)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	return c
+}
+
+func TestRegistration(t *testing.T) {
+	rp := New(testRPID, "Auth", testOrigin)
+	a := newAuthenticator(t)
+
+	c := register(t, rp, a)
+
+	if string(c.ID) != string(a.id) {
+		t.Errorf("credential id = %x, want %x", c.ID, a.id)
+	}
+
+	if c.SignCount != 0 {
+		t.Errorf("sign count = %d, want 0", c.SignCount)
+	}
+
+	// registered public key must verify assertions of authenticator
+	challenge := newChallenge(t)
+	cdj, ad, sig := a.get(challenge)
+
+	if _, err := rp.VerifyAssertion(challenge, c.PublicKey, c.SignCount, cdj, ad, sig); err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+}
+
+func TestRegistrationRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(a *authenticator)
+		wantErr error
+	}{
+		{
+			name:    "wrong origin",
+			modify:  func(a *authenticator) { a.origin = "https://evil.example" },
+			wantErr: ErrInvalidClientData,
+		},
+		{
+			name:    "wrong rp id hash",
+			modify:  func(a *authenticator) { a.rpID = "evil.example" },
+			wantErr: ErrInvalidAuthenticatorData,
+		},
+		{
+			name:    "user not present",
+			modify:  func(a *authenticator) { a.flags = flagUserVerified },
+			wantErr: ErrInvalidAuthenticatorData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := New(testRPID, "Auth", testOrigin)
+			a := newAuthenticator(t)
+			tt.modify(a)
+
+			challenge := newChallenge(t)
+			cdj, attObj := a.create(challenge)
+
+			if _, err := rp.VerifyRegistration(challenge, cdj, attObj); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyRegistration error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	rp := New(testRPID, "Auth", testOrigin)
+	a := newAuthenticator(t)
+	c := register(t, rp, a)
+
+	for i := 1; i <= 3; i++ {
+		challenge := newChallenge(t)
+		cdj, ad, sig := a.get(challenge)
+
+		signCount, err := rp.VerifyAssertion(challenge, c.PublicKey, c.SignCount, cdj, ad, sig)
+		if err != nil {
+			t.Fatalf("VerifyAssertion: %v", err)
+		}
+
+		if signCount != uint32(i) {
+			t.Fatalf("sign count = %d, want %d", signCount, i)
+		}
+
+		c.SignCount = signCount
+	}
+}
+
+func TestAssertionRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(a *authenticator)
+		wantErr error
+	}{
+		{
+			name:    "wrong origin",
+			modify:  func(a *authenticator) { a.origin = "https://evil.example" },
+			wantErr: ErrInvalidClientData,
+		},
+		{
+			name:    "wrong rp id hash",
+			modify:  func(a *authenticator) { a.rpID = "evil.example" },
+			wantErr: ErrInvalidAuthenticatorData,
+		},
+		{
+			name:    "user not present",
+			modify:  func(a *authenticator) { a.flags = flagUserVerified },
+			wantErr: ErrInvalidAuthenticatorData,
+		},
+		{
+			name:    "user not verified",
+			modify:  func(a *authenticator) { a.flags = flagUserPresent },
+			wantErr: ErrInvalidAuthenticatorData,
+		},
+		{
+			name: "other key",
+			modify: func(a *authenticator) {
+				a.key = newAuthenticator(a.t).key
+			},
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := New(testRPID, "Auth", testOrigin)
+			a := newAuthenticator(t)
+			c := register(t, rp, a)
+			tt.modify(a)
+
+			challenge := newChallenge(t)
+			cdj, ad, sig := a.get(challenge)
+
+			if _, err := rp.VerifyAssertion(challenge, c.PublicKey, c.SignCount, cdj, ad, sig); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyAssertion error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertionReplayedChallenge(t *testing.T) {
+	rp := New(testRPID, "Auth", testOrigin)
+	a := newAuthenticator(t)
+	c := register(t, rp, a)
+
+	challenge := newChallenge(t)
+	cdj, ad, sig := a.get(challenge)
+
+	signCount, err := rp.VerifyAssertion(challenge, c.PublicKey, c.SignCount, cdj, ad, sig)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+
+	// assertion signed for consumed challenge must not be accepted for new ceremony
+	if _, err = rp.VerifyAssertion(newChallenge(t), c.PublicKey, signCount, cdj, ad, sig); !errors.Is(err, ErrInvalidClientData) {
+		t.Fatalf("VerifyAssertion error = %v, want %v", err, ErrInvalidClientData)
+	}
+
+	// the same assertion replayed with the same challenge has stale signature counter
+	if _, err = rp.VerifyAssertion(challenge, c.PublicKey, signCount, cdj, ad, sig); !errors.Is(err, ErrCloned) {
+		t.Fatalf("VerifyAssertion error = %v, want %v", err, ErrCloned)
+	}
+}
+
+func TestAssertionSignCountRegression(t *testing.T) {
+	rp := New(testRPID, "Auth", testOrigin)
+	a := newAuthenticator(t)
+	c := register(t, rp, a)
+
+	// stored counter is ahead of authenticator, e.g. cloned authenticator was used
+	c.SignCount = 10
+	a.signCount = 4
+
+	challenge := newChallenge(t)
+	cdj, ad, sig := a.get(challenge)
+
+	if _, err := rp.VerifyAssertion(challenge, c.PublicKey, c.SignCount, cdj, ad, sig); !errors.Is(err, ErrCloned) {
+		t.Fatalf("VerifyAssertion error = %v, want %v", err, ErrCloned)
+	}
+}